@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Exit codes of the check and audit-missing actions, so a pipeline can tell
+// drift apart from a tool that could not run. Invalid flags exit with 2, both
+// the ones pflag rejects and the ones Complete rejects, and fatal setup errors
+// with 1.
+const (
+	exitCodeError = 1
+	exitCodeUsage = 2
+	exitCodeDrift = 3
+)
+
+// Exit codes of the check action with --diff-exit-code, following git diff
+// --exit-code: 0 no drift, 1 drift and 2 for errors, including invalid flags
+// and fatal setup errors.
+const (
+	diffExitCodeDrift = 1
+	diffExitCodeError = 2
+)
+
+// errorExitCode is the exit code of a run that could not finish.
+func (c *Config) errorExitCode() int {
+	if c.diffExitCode {
+		return diffExitCodeError
+	}
+	return exitCodeError
+}
+
+// fatalf logs a setup failure and exits with errorExitCode, unlike klog.Exitf
+// which always exits with 1, the drift code of --diff-exit-code.
+func (c *Config) fatalf(format string, args ...interface{}) {
+	klog.ErrorDepth(1, fmt.Sprintf(format, args...))
+	klog.Flush()
+	os.Exit(c.errorExitCode())
+}
+
+// exitUsage logs invalid flags and exits with exitCodeUsage.
+func exitUsage(format string, args ...interface{}) {
+	klog.ErrorDepth(1, fmt.Sprintf(format, args...))
+	klog.Flush()
+	os.Exit(exitCodeUsage)
+}
+
+// Result status values produced by the check action.
+const (
+	StatusMatched = "matched"
+	StatusDrifted = "drifted"
+)
+
+// Baseline maps namespace -> storageclass -> expected requests.storage quota.
+type Baseline map[string]map[string]string
+
+func loadBaseline(path string) (Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error happened when reading baseline file %s: %v", path, err)
+	}
+
+	var baseline Baseline
+	if err := yaml.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("error happened when parsing baseline file %s: %v", path, err)
+	}
+	for namespace, classes := range baseline {
+		for class, value := range classes {
+			if _, err := resource.ParseQuantity(value); err != nil {
+				return nil, fmt.Errorf("invalid value %q for storageclass/%s in namespace/%s: %v", value, class, namespace, err)
+			}
+		}
+	}
+
+	return baseline, nil
+}
+
+// CheckAgainstBaseline compares the storageclass quotas in the cluster with the
+// expectations in the baseline file. Every expectation produces a Result whose
+// Old is the actual value and New the expected one. Drift is reported through
+// the results only, the error is reserved for failures to run the check.
+// Namespaces of the baseline that are out of scope are skipped. It never
+// mutates anything.
+func (c *Config) CheckAgainstBaseline() ([]Result, error) {
+	baseline, err := loadBaseline(c.baselineFile)
+	if err != nil {
+		return nil, err
+	}
+
+	rqs, err := c.listResourceQuotas()
+	if err != nil {
+		return nil, err
+	}
+	byNamespace := map[string][]corev1.ResourceQuota{}
+	for _, rq := range rqs {
+		byNamespace[rq.Namespace] = append(byNamespace[rq.Namespace], rq)
+	}
+
+	namespaces := make([]string, 0, len(baseline))
+	for namespace := range baseline {
+		if c.outOfScope(namespace) {
+			klog.V(4).Infof("skip namespace/%s, it is out of scope", namespace)
+			continue
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	var results []Result
+	for _, namespace := range namespaces {
+		classes := make([]string, 0, len(baseline[namespace]))
+		for class := range baseline[namespace] {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+
+		for _, class := range classes {
+			result := c.checkExpectation(namespace, class, resource.MustParse(baseline[namespace][class]), byNamespace[namespace])
+			if result.Status != StatusMatched {
+				klog.Warningf("namespace/%s storageclass/%s: actual %q, expected %q (%s)", namespace, class, result.Old, result.New, result.Message)
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// countDrifted returns the number of results that do not match the baseline.
+func countDrifted(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Status == StatusDrifted {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *Config) checkExpectation(namespace, class string, expected resource.Quantity, rqs []corev1.ResourceQuota) Result {
+	result := Result{Namespace: namespace, Action: c.action, New: expected.String(), Status: StatusDrifted}
+	if len(rqs) == 0 {
+		result.Message = "no resourcequota in namespace"
+		return result
+	}
+
+	key := c.quotaKey(class)
+	for _, rq := range rqs {
+		actual, ok := hardValue(rq, key)
+		if !ok {
+			continue
+		}
+		result.Quota = rq.Name
+		result.Old = actual.String()
+		if quantitiesEqual(actual, expected) {
+			result.Status = StatusMatched
+			return result
+		}
+		result.Message = "value differs"
+		return result
+	}
+
+	result.Message = "key not set"
+	return result
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// checkpoint records every resourcequota that has been processed successfully,
+// one "namespace/name" per line, so that an interrupted run can be restarted
+// without touching them again.
+type checkpoint struct {
+	path string
+	done map[string]bool
+	file *os.File
+}
+
+// openCheckpoint loads the completed entries from path and opens it for appending.
+// When ignore is set any existing content is discarded.
+func openCheckpoint(path string, ignore bool) (*checkpoint, error) {
+	cp := &checkpoint{path: path, done: map[string]bool{}}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if ignore {
+		flags |= os.O_TRUNC
+	} else if data, err := os.ReadFile(path); err == nil {
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				cp.done[line] = true
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error happened when reading checkpoint file %s: %v", path, err)
+	}
+
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error happened when opening checkpoint file %s: %v", path, err)
+	}
+	cp.file = f
+
+	return cp, nil
+}
+
+// isDone and markDone are no-ops on a nil checkpoint.
+func (cp *checkpoint) isDone(namespace, name string) bool {
+	if cp == nil {
+		return false
+	}
+	return cp.done[namespace+"/"+name]
+}
+
+func (cp *checkpoint) markDone(namespace, name string) error {
+	if cp == nil {
+		return nil
+	}
+	entry := namespace + "/" + name
+	if _, err := fmt.Fprintln(cp.file, entry); err != nil {
+		return fmt.Errorf("error happened when writing checkpoint file %s: %v", cp.path, err)
+	}
+	cp.done[entry] = true
+
+	return nil
+}
+
+// finish closes the checkpoint and removes it when the run completed cleanly.
+func (cp *checkpoint) finish(clean bool) error {
+	if err := cp.file.Close(); err != nil {
+		return err
+	}
+	if clean {
+		return os.Remove(cp.path)
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+// requestContext derives the context of a single api request from the run
+// context, bounded by --per-request-timeout when it is set.
+func (c *Config) requestContext() (context.Context, context.CancelFunc) {
+	if c.perRequestTimeout <= 0 {
+		return c.context, func() {}
+	}
+	return context.WithTimeout(c.context, c.perRequestTimeout)
+}
+
+// checkRequestTimeout logs when ctx from requestContext hit its own deadline,
+// as opposed to the whole run timing out.
+func (c *Config) checkRequestTimeout(ctx context.Context, what string) {
+	if c.perRequestTimeout > 0 && ctx.Err() == context.DeadlineExceeded && c.context.Err() == nil {
+		klog.Warningf("%s timed out after per-request-timeout %v", what, c.perRequestTimeout)
+	}
+}
+
+// exitWithHint exits with a message explaining the most likely cause of a client
+// error. The raw error is only logged at -v=2.
+func (c *Config) exitWithHint(what string, err error) {
+	klog.V(2).Infof("%s: %v", what, err)
+	c.fatalf("%s: %s", what, clientHint(err))
+}
+
+// clientHint maps common client setup failures to an actionable hint.
+func clientHint(err error) string {
+	var urlErr *url.Error
+	var netErr net.Error
+	switch {
+	case os.IsNotExist(err) || errors.Is(err, os.ErrNotExist) || clientcmd.IsEmptyConfig(err):
+		return fmt.Sprintf("kubeconfig %s is missing or empty, copy a valid kubeconfig there and run `kubectl config view` to verify your context", clientcmd.RecommendedHomeFile)
+	case apierrors.IsUnauthorized(err):
+		return "the apiserver rejected the credentials, they may have expired; refresh them and run `kubectl config view` to verify your context"
+	case apierrors.IsForbidden(err):
+		return "the credentials are not allowed to perform the request, check the RBAC bindings of your user"
+	case errors.As(err, &urlErr), errors.As(err, &netErr):
+		return "the apiserver is unreachable, run `kubectl cluster-info` to verify the server address and your network"
+	}
+
+	return fmt.Sprintf("%v; run `kubectl config view` to verify your context", err)
+}
+
+// isTransient reports whether err is likely to go away when the request is retried.
+func isTransient(err error) bool {
+	var netErr net.Error
+	return apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsInternalError(err) || apierrors.IsServiceUnavailable(err) || errors.As(err, &netErr)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+)
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(2)
+	}
+}
+
+// newRootCommand wires the Config to a cobra command tree with one subcommand
+// per action. Running the root command without a subcommand keeps the old
+// --action behavior for one release.
+func newRootCommand() *cobra.Command {
+	config := new(Config)
+	lang := usageLang("zh")
+
+	root := &cobra.Command{
+		Use:     "storageclass-restrict",
+		Short:   "Restrict the usage of storage classes through ResourceQuotas",
+		Example: usageExamples(lang),
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config.Complete(cmd.Flags())
+			config.Start()
+		},
+	}
+
+	fs := root.PersistentFlags()
+	config.AddFlags(fs)
+	klog.InitFlags(nil)
+	fs.AddGoFlagSet(flag.CommandLine)
+
+	// The root command still runs every action through --action, so it takes the
+	// flags of all of them. Registering them also sets the defaults of the Config
+	// that the subcommands without those flags rely on.
+	fs = root.Flags()
+	config.AddActionFlag(fs)
+	_ = fs.MarkDeprecated("action", "use the add, remove, lint or check subcommand instead")
+	for _, addFlags := range []func(*pflag.FlagSet){
+		config.AddStorageclassFlags, config.AddValueFlags, config.AddLimitFlags, config.AddPatchFlags, config.AddSummaryFlags,
+		config.AddSyncFlags, config.AddImportFlags, config.AddCreateFlags, config.AddExportFlags, config.AddCheckFlags, config.AddReportFlags, config.AddOrphansFlags,
+	} {
+		addFlags(fs)
+	}
+
+	root.CompletionOptions.DisableDefaultCmd = true
+
+	for _, action := range []struct {
+		name  string
+		short string
+		long  string
+		flags []func(*pflag.FlagSet)
+	}{
+		{name: "add", short: "Add or update the storageclass quota in every ResourceQuota in scope",
+			flags: []func(*pflag.FlagSet){config.AddStorageclassFlags, config.AddValueFlags, config.AddLimitFlags, config.AddPatchFlags, config.AddSummaryFlags}},
+		{name: "remove", short: "Remove the storageclass quota from every ResourceQuota in scope",
+			flags: []func(*pflag.FlagSet){config.AddStorageclassFlags, config.AddPatchFlags, config.AddSummaryFlags}},
+		{name: "sync", short: "Apply the storageclass quotas of a template namespace to every ResourceQuota in scope",
+			flags: []func(*pflag.FlagSet){config.AddSyncFlags, config.AddCreateFlags, config.AddLimitFlags, config.AddPatchFlags, config.AddSummaryFlags}},
+		{name: "lint", short: "Report storageclass quota keys that are likely misconfigured", long: usageTexts[lang].lintExitCodes},
+		{name: "check", short: "Compare the storageclass quotas with a baseline file", long: usageTexts[lang].exitCodes,
+			flags: []func(*pflag.FlagSet){config.AddCheckFlags, config.AddSummaryFlags}},
+		{name: "orphans", short: "Report the quota keys of storage classes that do not exist, and remove them with --prune",
+			flags: []func(*pflag.FlagSet){config.AddOrphansFlags, config.AddPatchFlags, config.AddSummaryFlags}},
+		{name: "report", short: "Print the storageclass quota of every ResourceQuota in scope",
+			flags: []func(*pflag.FlagSet){config.AddStorageclassFlags, config.AddReportFlags, config.AddSummaryFlags}},
+		{name: "export", short: "Write the storageclass quotas of every namespace in scope to a baseline file",
+			flags: []func(*pflag.FlagSet){config.AddStorageclassFlags, config.AddExportFlags}},
+		{name: "import", short: "Apply the storageclass quotas of a file written by export",
+			flags: []func(*pflag.FlagSet){config.AddImportFlags, config.AddCreateFlags, config.AddLimitFlags, config.AddPatchFlags, config.AddSummaryFlags}},
+		{name: "audit-missing", short: "Report the namespaces that have no ResourceQuota", long: usageTexts[lang].auditExitCodes},
+		{name: "list-storageclasses", short: "List the storage classes with the quota allocated to each of them"},
+	} {
+		action := action
+		cmd := &cobra.Command{
+			Use:   action.name,
+			Short: action.short,
+			Long:  action.long,
+			Args:  cobra.NoArgs,
+			Run: func(cmd *cobra.Command, args []string) {
+				config.action = action.name
+				config.Complete(cmd.Flags())
+				config.Start()
+			},
+		}
+		for _, addFlags := range action.flags {
+			addFlags(cmd.Flags())
+		}
+		root.AddCommand(cmd)
+	}
+
+	return root
+}
+
+// quietLogs drops the info logs of klog for --quiet. Warnings and errors still
+// reach stderr through the stderr threshold, and discarding every severity keeps
+// klog from creating log files.
+func quietLogs() {
+	_ = flag.Set("logtostderr", "false")
+	_ = flag.Set("alsologtostderr", "false")
+	_ = flag.Set("stderrthreshold", "WARNING")
+	klog.SetOutput(io.Discard)
+}
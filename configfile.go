@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/pflag"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// applyConfigFile sets the flags of fs from the YAML file at path. Keys are flag
+// names; flags already given on the command line win over the file, and the
+// action cannot be set from it. List values are applied one element at a time,
+// as if the flag was repeated.
+func applyConfigFile(fs *pflag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error happened when reading config file %s: %v", path, err)
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("error happened when parsing config file %s: %v", path, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errorList []error
+	for _, key := range keys {
+		flag := fs.Lookup(key)
+		if flag == nil || key == "config" {
+			errorList = append(errorList, fmt.Errorf("unknown key %q", key))
+			continue
+		}
+		if key == "action" {
+			// The action comes from the subcommand, a file must not turn add into remove.
+			errorList = append(errorList, fmt.Errorf("key %q is not allowed, the action is given by the subcommand", key))
+			continue
+		}
+		if flag.Changed {
+			continue
+		}
+
+		items, ok := values[key].([]interface{})
+		if !ok {
+			items = []interface{}{values[key]}
+		}
+		for _, item := range items {
+			if err := fs.Set(key, fmt.Sprint(item)); err != nil {
+				errorList = append(errorList, fmt.Errorf("invalid value for %q: %v", key, err))
+			}
+		}
+	}
+
+	if len(errorList) != 0 {
+		return fmt.Errorf("invalid config file %s: %v", path, utilerrors.NewAggregate(errorList))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	resultConfigMapKey = "summary.json"
+	// maxConfigMapData stays below the 1MiB limit of a ConfigMap to leave room for metadata.
+	maxConfigMapData = 1000 * 1024
+)
+
+// parseNamespacedName splits "namespace/name".
+func parseNamespacedName(s string) (namespace, name string, ok bool) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// writeResultConfigMap stores the summary of the run in the --result-configmap,
+// creating it if needed. When the summary is too large for a ConfigMap the
+// per-result details are dropped and only the counts are kept.
+func (c *Config) writeResultConfigMap(summary Summary) error {
+	namespace, name, _ := parseNamespacedName(c.resultConfigMap)
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if len(data) > maxConfigMapData {
+		klog.Warningf("summary of %d bytes is too large for configmap/%s, only the counts are stored", len(data), name)
+		summary.Results = nil
+		if data, err = json.MarshalIndent(summary, "", "  "); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	configMaps := c.client.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{resultConfigMapKey: string(data)},
+		}
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{FieldManager: fieldManager})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[resultConfigMapKey] = string(data)
+	if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{FieldManager: fieldManager}); err != nil {
+		return fmt.Errorf("error happened when updating configmap/%s in namespace/%s: %v", name, namespace, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// scopeCounts is the size of the scope of a run, printed by --count-only.
+type scopeCounts struct {
+	namespaces     int
+	resourceQuotas int
+	keys           int
+	atTarget       int
+}
+
+func (s scopeCounts) String() string {
+	return fmt.Sprintf("%d namespaces, %d resourcequotas, %d of %d storageclass quotas already at the target", s.namespaces, s.resourceQuotas, s.atTarget, s.keys)
+}
+
+// CountScope lists and filters the ResourceQuotas in scope like a run would and
+// counts them, without rendering or sending any patch.
+func (c *Config) CountScope() (scopeCounts, error) {
+	var counts scopeCounts
+	namespaces := map[string]bool{}
+	err := c.forEachResourceQuotaPage(func(rqs []corev1.ResourceQuota) error {
+		for _, rq := range rqs {
+			namespaces[rq.Namespace] = true
+			counts.resourceQuotas++
+			for _, class := range c.storageclasses {
+				counts.keys++
+				// A run skips the resourcequotas without the source key, they are never at the target.
+				if c.action == "add" && c.sourceKey != "" && getExistingStorageQuota(rq, c.sourceKey) == nil {
+					continue
+				}
+				want, err := c.targetFor(rq, class)
+				if err == nil && isAlreadyAtTarget(rq, c.quotaKey(class), want) {
+					counts.atTarget++
+				}
+			}
+		}
+		return nil
+	})
+	counts.namespaces = len(namespaces)
+
+	return counts, err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/klog/v2"
+)
+
+// discoverQuotaKeyFormat derives the quota key format from the API group that
+// serves the StorageClass kind. Anything but storage.k8s.io is only warned about
+// and keeps the default format. The discovered group is cached in the Config.
+func (c *Config) discoverQuotaKeyFormat() {
+	if c.quotaGroup == "" {
+		group, err := c.discoverStorageclassGroup()
+		if err != nil {
+			klog.Warningf("failed to discover the storageclass api group, fall back to %s: %v", defaultQuotaKeyFormat, err)
+			return
+		}
+		c.quotaGroup = group
+	}
+
+	c.keyFormat = quotaKeyFormatFor("storageclass." + c.quotaGroup)
+	klog.V(2).Infof("discovered quota key format %s", c.keyFormat)
+}
+
+// storageclassAPIGroup is the group of the default quota key format.
+const storageclassAPIGroup = "storage.k8s.io"
+
+func (c *Config) discoverStorageclassGroup() (string, error) {
+	// Partial results are still usable when some aggregated api is unavailable.
+	lists, err := c.client.Discovery().ServerPreferredResources()
+	group, groupErr := storageclassGroup(lists)
+	if groupErr != nil && err != nil {
+		return "", err
+	}
+	return group, groupErr
+}
+
+// storageclassGroup picks the API group serving the StorageClass kind from the
+// discovered lists. A CRD that happens to be named storageclasses in another group
+// must not rewrite the quota keys, so storage.k8s.io wins whenever it is served and
+// any other outcome is an error that keeps the default.
+func storageclassGroup(lists []*metav1.APIResourceList) (string, error) {
+	groups := map[string]bool{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if r.Name == "storageclasses" && r.Kind == "StorageClass" {
+				groups[gv.Group] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+	switch {
+	case groups[storageclassAPIGroup]:
+		return storageclassAPIGroup, nil
+	case len(names) == 0:
+		return "", fmt.Errorf("no api group serves storageclasses")
+	case len(names) > 1:
+		return "", fmt.Errorf("several api groups serve storageclasses (%s) and none is %s", strings.Join(names, ", "), storageclassAPIGroup)
+	default:
+		return "", fmt.Errorf("storageclasses are served by %s instead of %s", names[0], storageclassAPIGroup)
+	}
+}
+
+// The range of cluster versions the <class>.storageclass.storage.k8s.io quota
+// keys are tested against.
+var (
+	minTestedVersion = utilversion.MustParseGeneric("1.16")
+	maxTestedVersion = utilversion.MustParseGeneric("1.30")
+)
+
+// checkServerVersion logs the version of the cluster and warns when it is
+// outside the tested range, which exits instead with --strict-version.
+func (c *Config) checkServerVersion(info *version.Info) {
+	c.serverVersion = info.GitVersion
+	klog.Infof("connected to %s, kubernetes %s (%s)", c.host, info.GitVersion, info.Platform)
+
+	v, err := utilversion.ParseGeneric(info.GitVersion)
+	if err != nil {
+		klog.Warningf("failed to parse server version %s: %v", info.GitVersion, err)
+		return
+	}
+	minor := utilversion.MustParseGeneric(fmt.Sprintf("%d.%d", v.Major(), v.Minor()))
+	if minor.AtLeast(minTestedVersion) && !maxTestedVersion.LessThan(minor) {
+		return
+	}
+	if c.strictVersion {
+		c.fatalf("kubernetes %s is outside the tested range %s - %s, the storageclass quota keys may be rejected", info.GitVersion, minTestedVersion, maxTestedVersion)
+	}
+	klog.Warningf("kubernetes %s is outside the tested range %s - %s, the storageclass quota keys may be rejected", info.GitVersion, minTestedVersion, maxTestedVersion)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStorageclassGroup(t *testing.T) {
+	storageclasses := func(groupVersion, kind string) *metav1.APIResourceList {
+		return &metav1.APIResourceList{GroupVersion: groupVersion, APIResources: []metav1.APIResource{{Name: "storageclasses", Kind: kind}}}
+	}
+
+	tests := []struct {
+		name    string
+		lists   []*metav1.APIResourceList
+		want    string
+		wantErr bool
+	}{
+		{name: "default", lists: []*metav1.APIResourceList{storageclasses("storage.k8s.io/v1", "StorageClass")}, want: "storage.k8s.io"},
+		{name: "default wins over a crd", lists: []*metav1.APIResourceList{storageclasses("example.com/v1", "StorageClass"), storageclasses("storage.k8s.io/v1", "StorageClass")}, want: "storage.k8s.io"},
+		{name: "other kind", lists: []*metav1.APIResourceList{storageclasses("example.com/v1", "StorageClassPolicy")}, wantErr: true},
+		{name: "other group", lists: []*metav1.APIResourceList{storageclasses("example.com/v1", "StorageClass")}, wantErr: true},
+		{name: "ambiguous", lists: []*metav1.APIResourceList{storageclasses("example.com/v1", "StorageClass"), storageclasses("example.org/v1", "StorageClass")}, wantErr: true},
+		{name: "none", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := storageclassGroup(tt.lists)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("storageclassGroup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("storageclassGroup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
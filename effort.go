@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+// effortPresets are the values --effort gives to the reliability and
+// performance flags that are not set explicitly:
+//
+//	low:    --retries=0 --per-request-timeout=10s --qps=5  --burst=10
+//	normal: --retries=3 --per-request-timeout=30s --qps=20 --burst=40
+//	high:   --retries=8 --per-request-timeout=2m  --qps=50 --burst=100
+var effortPresets = map[string]struct {
+	retries           int
+	perRequestTimeout time.Duration
+	qps               float32
+	burst             int
+}{
+	"low":    {retries: 0, perRequestTimeout: 10 * time.Second, qps: 5, burst: 10},
+	"normal": {retries: 3, perRequestTimeout: 30 * time.Second, qps: 20, burst: 40},
+	"high":   {retries: 8, perRequestTimeout: 2 * time.Minute, qps: 50, burst: 100},
+}
+
+// applyEffort fills in the flags of the --effort preset, flags given on the
+// command line or in the config file win.
+func (c *Config) applyEffort(fs *pflag.FlagSet) error {
+	preset, ok := effortPresets[c.effort]
+	if !ok {
+		return fmt.Errorf("effort must be low, normal or high,and you provide %s", c.effort)
+	}
+
+	values := map[string]string{
+		"retries":             strconv.Itoa(preset.retries),
+		"per-request-timeout": preset.perRequestTimeout.String(),
+		"qps":                 strconv.FormatFloat(float64(preset.qps), 'f', -1, 32),
+		"burst":               strconv.Itoa(preset.burst),
+	}
+	for name, value := range values {
+		if fs.Changed(name) {
+			continue
+		}
+		if err := fs.Lookup(name).Value.Set(value); err != nil {
+			return fmt.Errorf("invalid %s %s of effort %s: %v", name, value, c.effort, err)
+		}
+	}
+	klog.V(2).Infof("effort %s: retries %d, per-request-timeout %v, qps %v, burst %d", c.effort, c.retries, c.perRequestTimeout, c.qps, c.burst)
+	return nil
+}
+
+// backoff returns the backoff for retrying transient api errors --retries times.
+func (c *Config) backoff() wait.Backoff {
+	b := retry.DefaultBackoff
+	b.Steps = c.retries + 1
+	return b
+}
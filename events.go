@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// eventReason is the reason of the events recorded on patched ResourceQuotas.
+const eventReason = "StorageclassQuotaPatched"
+
+// emitEvent records a Normal event with the action, --reason and --ticket of the
+// run on the patched rq, so kubectl describe ties the change to its change record.
+// Custom resources of --dynamic-resource get no event, and a failure is only logged.
+func (c *Config) emitEvent(rq corev1.ResourceQuota) {
+	if c.quotaResource != resourceQuotaGVR {
+		klog.V(2).Infof("skip the event of resourcequota/%s in namespace/%s, it is not a core resourcequota", rq.Name, rq.Namespace)
+		return
+	}
+
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: rq.Name + ".", Namespace: rq.Namespace},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion:      "v1",
+			Kind:            "ResourceQuota",
+			Namespace:       rq.Namespace,
+			Name:            rq.Name,
+			UID:             rq.UID,
+			ResourceVersion: rq.ResourceVersion,
+		},
+		Reason:         eventReason,
+		Message:        c.eventMessage(),
+		Source:         corev1.EventSource{Component: fieldManager},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           corev1.EventTypeNormal,
+	}
+
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	if _, err := c.client.CoreV1().Events(rq.Namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		klog.Warningf("failed to record the event of resourcequota/%s in namespace/%s: %v", rq.Name, rq.Namespace, err)
+	}
+}
+
+// eventMessage describes the action of the run along with --reason and --ticket.
+func (c *Config) eventMessage() string {
+	parts := []string{c.managedAnnotations()[annotationLastAction]}
+	if c.reason != "" {
+		parts = append(parts, "reason: "+c.reason)
+	}
+	if c.ticket != "" {
+		parts = append(parts, "ticket: "+c.ticket)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// ExportStorageclassQuotas collects the requests.storage quota of every
+// storageclass in the ResourceQuotas in scope, limited to --storageclass or
+// --storageclass-pattern when given. The result has the shape of a baseline
+// file, so it can be fed back to the check action. When several ResourceQuotas
+// of a namespace limit the same storageclass the first one by name wins, as in
+// the check action. It never mutates anything.
+func (c *Config) ExportStorageclassQuotas() (Baseline, error) {
+	classes := map[string]bool{}
+	for _, class := range c.storageclasses {
+		classes[class] = true
+	}
+
+	var rqs []corev1.ResourceQuota
+	err := c.forEachResourceQuotaPage(func(items []corev1.ResourceQuota) error {
+		rqs = append(rqs, items...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(rqs, func(i, j int) bool { return rqs[i].Name < rqs[j].Name })
+
+	exported := Baseline{}
+	for _, rq := range rqs {
+		for name, q := range hardLimits(rq) {
+			class, suffix, ok := parseQuotaKey(c.keyFormat, string(name))
+			if !ok || suffix != requestsStorageSuffix || (len(classes) != 0 && !classes[class]) {
+				continue
+			}
+			if exported[rq.Namespace] == nil {
+				exported[rq.Namespace] = map[string]string{}
+			}
+			if existing, ok := exported[rq.Namespace][class]; ok {
+				klog.Warningf("namespace/%s limits storageclass/%s in more than one resourcequota, export %s and ignore %s of resourcequota/%s", rq.Namespace, class, existing, q.String(), rq.Name)
+				continue
+			}
+			exported[rq.Namespace][class] = q.String()
+		}
+	}
+
+	return exported, nil
+}
+
+// writeExport writes exported to --output-file, or to stdout without it. The
+// file is YAML when its name ends with .yaml or .yml and JSON otherwise.
+func (c *Config) writeExport(exported Baseline) error {
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return err
+	}
+	if ext := filepath.Ext(c.outputFile); ext == ".yaml" || ext == ".yml" {
+		if data, err = yaml.JSONToYAML(data); err != nil {
+			return err
+		}
+	} else {
+		data = append(data, '\n')
+	}
+
+	if c.outputFile == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(c.outputFile, data, 0o644)
+}
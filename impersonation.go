@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+
+	"k8s.io/client-go/rest"
+)
+
+// impersonateUIDHeader is not known to this version of client-go, so it is
+// added to the requests by a wrapping round tripper.
+const impersonateUIDHeader = "Impersonate-Uid"
+
+func (c *Config) impersonating() bool {
+	return c.asUser != "" || len(c.asGroups) != 0 || c.asUID != ""
+}
+
+// applyImpersonation makes every request built from rc act as the identity given by --as, --as-group and --as-uid.
+func (c *Config) applyImpersonation(rc *rest.Config) {
+	rc.Impersonate = rest.ImpersonationConfig{
+		UserName: c.asUser,
+		Groups:   c.asGroups,
+	}
+	if c.asUID != "" {
+		uid := c.asUID
+		rc.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &uidImpersonatingRoundTripper{uid: uid, delegate: rt}
+		})
+	}
+}
+
+type uidImpersonatingRoundTripper struct {
+	uid      string
+	delegate http.RoundTripper
+}
+
+func (rt *uidImpersonatingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(impersonateUIDHeader, rt.uid)
+	return rt.delegate.RoundTrip(req)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+// importedQuotaName is the name of the ResourceQuotas the import action creates
+// with --create-if-missing.
+const importedQuotaName = "storageclass-restrict"
+
+// loadImport reads the --input-file written by export and decides which
+// ResourceQuota each of its values goes to: the first one by name in the namespace
+// that already limits the storageclass, or else the first one by name. Entries
+// out of scope are dropped, and the namespaces without any ResourceQuota are kept
+// for createImportedQuotas.
+func (c *Config) loadImport() {
+	manifest, err := loadBaseline(c.inputFile)
+	if err != nil {
+		c.fatalf("%v", err)
+	}
+
+	only := map[string]bool{}
+	for _, class := range c.storageclasses {
+		only[class] = true
+	}
+	c.imported = Baseline{}
+	classes := map[string]bool{}
+	for namespace, values := range manifest {
+		if c.outOfScope(namespace) {
+			klog.V(4).Infof("skip namespace/%s, it is out of scope", namespace)
+			continue
+		}
+		for class, value := range values {
+			if len(only) != 0 && !only[class] {
+				continue
+			}
+			if c.imported[namespace] == nil {
+				c.imported[namespace] = map[string]string{}
+			}
+			c.imported[namespace][class] = value
+			classes[class] = true
+		}
+	}
+	if len(c.imported) == 0 {
+		c.fatalf("input file %s has no storageclass quota in scope", c.inputFile)
+	}
+
+	c.storageclasses = nil
+	for class := range classes {
+		c.storageclasses = append(c.storageclasses, class)
+	}
+	sort.Strings(c.storageclasses)
+
+	byNamespace := map[string][]corev1.ResourceQuota{}
+	for namespace := range c.imported {
+		ctx, cancel := c.requestContext()
+		rqs, err := c.listQuotas(ctx, namespace, c.quotaListOptions())
+		c.checkRequestTimeout(ctx, "list resourcequotas in namespace/"+namespace)
+		cancel()
+		if err != nil {
+			c.fatalf("error happened when list resourcequotas of namespace/%s,error: %v", namespace, err.Error())
+		}
+		sort.Slice(rqs.Items, func(i, j int) bool { return rqs.Items[i].Name < rqs.Items[j].Name })
+		byNamespace[namespace] = rqs.Items
+	}
+
+	c.importTargets = map[string]string{}
+	for namespace, values := range c.imported {
+		rqs := byNamespace[namespace]
+		if len(rqs) == 0 {
+			continue
+		}
+		for class := range values {
+			c.importTargets[namespace+"/"+class] = rqs[0].Name
+			for _, rq := range rqs {
+				if _, ok := hardValue(rq, c.quotaKey(class)); ok {
+					c.importTargets[namespace+"/"+class] = rq.Name
+					break
+				}
+			}
+		}
+	}
+	klog.Infof("input file %s has %d storageclass quotas in %d namespaces in scope", c.inputFile, len(c.importTargets), len(c.imported))
+}
+
+// importsTo reports whether the imported value of class in the namespace of rq goes to rq.
+func (c *Config) importsTo(rq corev1.ResourceQuota, class string) bool {
+	return c.importTargets[rq.Namespace+"/"+class] == rq.Name
+}
+
+// createImportedQuotas handles the namespaces of the input file that have no
+// ResourceQuota: with --create-if-missing one is created with their values,
+// otherwise they are skipped with a warning.
+func (c *Config) createImportedQuotas() ([]Result, error) {
+	namespaces := make([]string, 0, len(c.imported))
+	for namespace := range c.imported {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	var results []Result
+	var errorList []error
+	for _, namespace := range namespaces {
+		values := c.imported[namespace]
+		missing := false
+		for class := range values {
+			if _, ok := c.importTargets[namespace+"/"+class]; !ok {
+				missing = true
+			}
+		}
+		if !missing {
+			continue
+		}
+
+		if !c.createIfMissing {
+			klog.Warningf("skip namespace/%s, it has no resourcequota (use --create-if-missing)", namespace)
+			results = append(results, Result{Namespace: namespace, Action: c.action, Status: StatusSkipped, Message: "no resourcequota in namespace"})
+			continue
+		}
+		hard := corev1.ResourceList{}
+		for class, value := range values {
+			hard[corev1.ResourceName(c.quotaKey(class))] = resource.MustParse(value)
+		}
+		result, err := c.createQuota(namespace, importedQuotaName, hard)
+		if apierrors.IsNotFound(err) {
+			klog.Warningf("skip namespace/%s, it no longer exists", namespace)
+			result.Status = StatusSkipped
+			result.Message = "namespace not found"
+			err = nil
+		}
+		results = append(results, result)
+		if err != nil {
+			errorList = append(errorList, fmt.Errorf("create resourcequota/%s in namespace/%s: %v", importedQuotaName, namespace, err))
+			if c.exitOnFirstError {
+				break
+			}
+		}
+	}
+
+	return results, utilerrors.NewAggregate(errorList)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+// journalEntry records the hard limits a patch overwrote, nil for a key that was not set.
+type journalEntry struct {
+	namespace string
+	name      string
+	previous  map[string]*resource.Quantity
+}
+
+// record adds the values that changes is about to overwrite in rq to the journal
+// of --transactional.
+func (c *Config) record(rq corev1.ResourceQuota, changes map[string]*resource.Quantity) {
+	previous := make(map[string]*resource.Quantity, len(changes))
+	for key := range changes {
+		if q, ok := hardValue(rq, key); ok {
+			q := q.DeepCopy()
+			previous[key] = &q
+			continue
+		}
+		previous[key] = nil
+	}
+	c.journal = append(c.journal, journalEntry{namespace: rq.Namespace, name: rq.Name, previous: previous})
+}
+
+// rollback reverts the patches in the journal, the most recent first, and marks
+// their results as rolled back. Only the hard limits are reverted, the managed
+// annotations and labels are left as they are. It returns the reverts that failed.
+func (c *Config) rollback(results []Result) error {
+	klog.Warningf("the run aborted, roll back %d patched resourcequotas", len(c.journal))
+	var errorList []error
+	for i := len(c.journal) - 1; i >= 0; i-- {
+		entry := c.journal[i]
+		hard := map[string]interface{}{}
+		for key, q := range entry.previous {
+			if q == nil {
+				hard[key] = nil
+				continue
+			}
+			hard[key] = q.String()
+		}
+		data, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"hard": hard}})
+		if err != nil {
+			errorList = append(errorList, err)
+			continue
+		}
+
+		// The run context may be the reason of the abort, the reverts get their own.
+		err = retry.OnError(c.backoff(), isTransient, func() error {
+			ctx, cancel := context.WithCancel(context.Background())
+			if c.perRequestTimeout > 0 {
+				ctx, cancel = context.WithTimeout(context.Background(), c.perRequestTimeout)
+			}
+			defer cancel()
+			return c.patch(ctx, entry.namespace, entry.name, types.MergePatchType, data)
+		})
+		if err != nil {
+			klog.Errorf("failed to roll back resourcequota/%s in namespace/%s: %v", entry.name, entry.namespace, err)
+			errorList = append(errorList, fmt.Errorf("roll back resourcequota/%s in namespace/%s: %v", entry.name, entry.namespace, err))
+			continue
+		}
+		klog.Infof("rolled back resourcequota/%s in namespace/%s: %s", entry.name, entry.namespace, data)
+		for j := range results {
+			if results[j].Namespace == entry.namespace && results[j].Quota == entry.name && results[j].Status == StatusPatched {
+				results[j].Status = StatusRolledBack
+			}
+		}
+	}
+	c.journal = nil
+
+	return utilerrors.NewAggregate(errorList)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+// Annotations of the lease recording which run the last leader claimed.
+const (
+	annotationClaimedRun = "storageclass-restrict.tiggoins.io/claimed-run"
+	annotationClaimedAt  = "storageclass-restrict.tiggoins.io/claimed-at"
+)
+
+// Start runs the action of the Config. With --enable-leader-election a mutating
+// action only runs once this replica holds the lease, and only if no other
+// replica claimed the same run before: the leader records --run-id and the time
+// in the lease before it patches anything. A replica that gets the lease later
+// exits without patching when the lease names its --run-id, or without
+// --run-id when the run was claimed after this replica started. The lease is
+// released when the run is over.
+func (c *Config) Start() {
+	if !c.enableLeaderElection || !c.mutates() || c.countOnly {
+		c.Run()
+		return
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	identity := fmt.Sprintf("%s_%s", hostname, uuid.NewUUID())
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Namespace: c.leaseNamespace, Name: c.leaseName},
+		Client:     c.client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	klog.Infof("waiting for lease/%s in namespace/%s as %s", c.leaseName, c.leaseNamespace, identity)
+	leaderelection.RunOrDie(c.context, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   c.leaseDuration,
+		RenewDeadline:   c.renewDeadline,
+		RetryPeriod:     c.retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            c.leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				klog.Infof("acquired lease/%s in namespace/%s", c.leaseName, c.leaseNamespace)
+				claimed, err := c.claimRun(ctx)
+				if err != nil {
+					c.fatalf("failed to claim the run in lease/%s in namespace/%s: %v", c.leaseName, c.leaseNamespace, err)
+				}
+				if !claimed {
+					c.cancel()
+					return
+				}
+				// Run cancels c.context when it returns, which releases the lease.
+				c.Run()
+			},
+			OnStoppedLeading: func() {
+				if c.context.Err() == nil {
+					c.fatalf("lost lease/%s in namespace/%s before the run finished", c.leaseName, c.leaseNamespace)
+				}
+			},
+		},
+	})
+}
+
+// claimRun records this run in the annotations of the lease held by this
+// replica. It returns false when another replica already claimed the same run.
+func (c *Config) claimRun(ctx context.Context) (bool, error) {
+	claimed := true
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		leases := c.client.CoordinationV1().Leases(c.leaseNamespace)
+		lease, err := leases.Get(ctx, c.leaseName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		run, at := lease.Annotations[annotationClaimedRun], lease.Annotations[annotationClaimedAt]
+		if c.runID != "" && run == c.runID {
+			klog.Infof("run %s was already claimed at %s, exit without patching", c.runID, at)
+			claimed = false
+			return nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, at); c.runID == "" && err == nil && t.After(c.startedAt) {
+			klog.Infof("another replica claimed a run at %s, after this replica started, exit without patching", at)
+			claimed = false
+			return nil
+		}
+
+		if lease.Annotations == nil {
+			lease.Annotations = map[string]string{}
+		}
+		lease.Annotations[annotationClaimedRun] = c.runID
+		lease.Annotations[annotationClaimedAt] = time.Now().UTC().Format(time.RFC3339Nano)
+		_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+		return err
+	})
+
+	return claimed, err
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// knownStorageclassSuffixes are the resources that can be restricted per storageclass.
+var knownStorageclassSuffixes = map[string]bool{
+	"requests.storage":       true,
+	"persistentvolumeclaims": true,
+}
+
+// LintFinding describes a suspicious key found in a ResourceQuota.
+type LintFinding struct {
+	Namespace string
+	Quota     string
+	Key       string
+	Message   string
+}
+
+// LintStorageclassQuotas scans the ResourceQuotas in scope for storageclass keys
+// that are likely misconfigured. The findings are reported through the result only,
+// the error is reserved for failures to list. It never mutates anything.
+func (c *Config) LintStorageclassQuotas() ([]LintFinding, error) {
+	rqs, err := c.listResourceQuotas()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []LintFinding
+	for _, rq := range rqs {
+		findings = append(findings, lintResourceQuota(c.keyFormat, rq)...)
+	}
+
+	for _, f := range findings {
+		klog.Warningf("namespace/%s resourcequota/%s key %q: %s", f.Namespace, f.Quota, f.Key, f.Message)
+	}
+	return findings, nil
+}
+
+func lintResourceQuota(format string, rq corev1.ResourceQuota) []LintFinding {
+	var findings []LintFinding
+	for name := range hardLimits(rq) {
+		key := string(name)
+		_, suffix, ok := parseQuotaKey(format, key)
+		if !ok {
+			continue
+		}
+
+		if !knownStorageclassSuffixes[suffix] {
+			findings = append(findings, LintFinding{
+				Namespace: rq.Namespace,
+				Quota:     rq.Name,
+				Key:       key,
+				Message:   fmt.Sprintf("unrecognized resource suffix %q, expected requests.storage or persistentvolumeclaims", suffix),
+			})
+		}
+	}
+
+	for _, variants := range keyVariants(rq) {
+		if len(variants) < 2 {
+			continue
+		}
+		if _, _, ok := parseQuotaKey(format, normalizeQuotaKey(variants[0])); !ok {
+			continue
+		}
+		for _, key := range variants {
+			findings = append(findings, LintFinding{
+				Namespace: rq.Namespace,
+				Quota:     rq.Name,
+				Key:       key,
+				Message:   fmt.Sprintf("differs only by case or whitespace from %s", strings.Join(otherKeys(variants, key), ", ")),
+			})
+		}
+	}
+
+	return findings
+}
+
+// normalizeQuotaKey folds the differences hand-edited quota keys tend to have.
+func normalizeQuotaKey(key string) string {
+	return strings.ToLower(strings.TrimSpace(key))
+}
+
+// keyVariants groups the hard keys of rq by their normalized form, each group sorted.
+func keyVariants(rq corev1.ResourceQuota) map[string][]string {
+	variants := map[string][]string{}
+	for name := range hardLimits(rq) {
+		key := string(name)
+		variants[normalizeQuotaKey(key)] = append(variants[normalizeQuotaKey(key)], key)
+	}
+	for _, keys := range variants {
+		sort.Strings(keys)
+	}
+
+	return variants
+}
+
+// warnKeyVariants warns when rq has keys that only differ from key by case or
+// whitespace, the patch only targets key itself and leaves them in place.
+func warnKeyVariants(rq corev1.ResourceQuota, key string) {
+	if others := otherKeys(keyVariants(rq)[normalizeQuotaKey(key)], key); len(others) != 0 {
+		klog.Warningf("resourcequota/%s in namespace/%s has keys %q that differ from %s only by case or whitespace, they are not changed", rq.Name, rq.Namespace, others, key)
+	}
+}
+
+func otherKeys(keys []string, key string) []string {
+	var others []string
+	for _, k := range keys {
+		if k != key {
+			others = append(others, k)
+		}
+	}
+	return others
+}
@@ -2,17 +2,28 @@ package main
 
 import (
 	"context"
-	"flag"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 
 	"github.com/spf13/pflag"
@@ -25,146 +36,1563 @@ type Config struct {
 	action       string
 	namespace    string
 	size         string
+	force        bool
+	keyFormat    string
+	skipRBAC     bool
+	sortBy       string
+	resumeFrom   string
+
+	checkpointFile   string
+	ignoreCheckpoint bool
+
+	asUser   string
+	asGroups []string
+	asUID    string
+
+	onlyWithPVCs bool
+	onlyIfUnused bool
+
+	minValue string
+	maxValue string
+	min      *resource.Quantity
+	max      *resource.Quantity
+
+	excludeNamespaces []string
+	quotaSelector     string
+	namespacePrefix   string
+	platform          string
+
+	namespaces     []string
+	namespacesFile string
+
+	annotateManaged bool
+	labelManaged    string
+	labelKey        string
+	labelValue      string
+	annotationsOnly bool
+
+	baselineFile string
+
+	timing  bool
+	timings *timings
+
+	target          string
+	targetNamespace string
+	targetName      string
+
+	lang string
+
+	exitOnFirstError bool
+
+	storageclassPattern string
+	storageclasses      []string
+	// storageclassObjects caches the StorageClasses fetched during validation,
+	// for --output wide.
+	storageclassObjects map[string]storagev1.StorageClass
+
+	host          string
+	serverVersion string
+	strictVersion bool
+
+	startedAt   time.Time
+	summaryFile string
+	outputFile  string
+	inputFile   string
+	// imported holds the in-scope values of --input-file for the import action
+	// and importTargets the ResourceQuota each namespace/storageclass goes to.
+	imported      Baseline
+	importTargets map[string]string
+
+	quotaGroup string
+	// storageclassAPIVersion is the group portion of the quota key, between the
+	// storageclass name and the resource suffix.
+	storageclassAPIVersion string
+
+	sanityMaxValue string
+	sanityMax      resource.Quantity
+
+	reason string
+	ticket string
+
+	enableLeaderElection bool
+	leaseName            string
+	leaseNamespace       string
+	leaseDuration        time.Duration
+	renewDeadline        time.Duration
+	retryPeriod          time.Duration
+	runID                string
+
+	increaseOnly bool
+	safe         bool
+
+	listLimit int64
+
+	sourceKey string
+
+	templateNamespace string
+	templateQuotaName string
+	templateQuotas    map[string]resource.Quantity
+	createIfMissing   bool
+
+	sinceResourceVersion   string
+	currentResourceVersion string
+
+	failIfNoQuotas bool
+
+	notifyURL     string
+	notifyTimeout time.Duration
+	notifyRetries int
+
+	redacted     bool
+	redactionMap string
+	redactions   map[string]string
+
+	quiet          bool
+	successMessage string
+
+	distribution bool
+	prune        bool
+	printKubectl bool
+
+	printStorageclass bool
+	skipSCCheck       bool
+	relativeChange    bool
+
+	deltaValue string
+	delta      *resource.Quantity
+	assumeZero bool
+
+	sizePercent float64
+
+	cancel            context.CancelFunc
+	timeout           time.Duration
+	perRequestTimeout time.Duration
+	effort            string
+	retries           int
+	qps               float32
+	burst             int
+
+	output     string
+	outputSort string
+
+	optimistic bool
+
+	diffExitCode bool
+
+	allowedWindow string
+
+	checkOwnership bool
+
+	dryRun bool
+
+	configFile string
+
+	patchType string
+
+	groupByLabel string
+	groupPause   time.Duration
+
+	pauseBetween         time.Duration
+	lastPatchedNamespace string
+
+	transactional     bool
+	countOnly         bool
+	reconcileAfterRun bool
+	journal           []journalEntry
+
+	resultConfigMap string
+
+	requireBinarySI bool
+
+	dynamic         bool
+	dynamicResource string
+	quotaResource   schema.GroupVersionResource
+	dynamicClient   dynamic.Interface
 }
 
 var (
-	patchAddTemplate = `{
-		"spec": {
-			"hard": {
-				"%s.storageclass.storage.k8s.io/requests.storage": "%s"
-			}
+	defaultQuotaKeyFormat         = "%s.storageclass.storage.k8s.io/%s"
+	defaultStorageclassAPIVersion = "storageclass.storage.k8s.io"
+	requestsStorageSuffix         = "requests.storage"
+)
+
+// Run performs the action of the Config and reports the outcome.
+func (c *Config) Run() {
+	defer c.cancel()
+	var errorList []error
+	if c.action == "lint" {
+		findings, err := c.LintStorageclassQuotas()
+		if err != nil {
+			klog.Errorf("Errors occurred: %v\n", err)
+			klog.Flush()
+			os.Exit(c.errorExitCode())
+		}
+		if len(findings) != 0 {
+			klog.Warningf("found %d suspicious storageclass quota keys", len(findings))
+			klog.Flush()
+			os.Exit(exitCodeDrift)
+		}
+		c.succeed("no suspicious storageclass quota keys found.")
+		return
+	}
+	if c.action == "list-storageclasses" {
+		usages, err := c.ListStorageclassUsage()
+		if err != nil {
+			klog.Errorf("Errors occurred: %v\n", err)
+			klog.Flush()
+			os.Exit(exitCodeError)
+		}
+		if err := c.printStorageclassUsage(usages); err != nil {
+			klog.Warningf("failed to print storageclasses: %v", err)
 		}
-	
-	}`
+		return
+	}
+	if c.action == "audit-missing" {
+		missing, err := c.NamespacesWithoutQuota()
+		if err != nil {
+			klog.Errorf("Errors occurred: %v\n", err)
+			klog.Flush()
+			os.Exit(exitCodeError)
+		}
+		for _, ns := range missing {
+			klog.Warningf("namespace/%s has no resourcequota, its storage usage is not restricted", ns)
+		}
+		if len(missing) != 0 {
+			klog.Warningf("%d namespaces have no resourcequota", len(missing))
+			klog.Flush()
+			os.Exit(exitCodeDrift)
+		}
+		c.succeed("every namespace has a resourcequota.")
+		return
+	}
+	if c.action == "export" {
+		exported, err := c.ExportStorageclassQuotas()
+		if err == nil {
+			err = c.writeExport(exported)
+		}
+		if err != nil {
+			klog.Errorf("Errors occurred: %v\n", err)
+			klog.Flush()
+			os.Exit(exitCodeError)
+		}
+		klog.Infof("exported the storageclass quotas of %d namespaces", len(exported))
+		return
+	}
+	if c.action == "report" {
+		results, err := c.ReportStorageclassQuotas()
+		c.saveSummary(results, err)
+		if err != nil {
+			klog.Errorf("Errors occurred: %v\n", err)
+			klog.Flush()
+			os.Exit(exitCodeError)
+		}
+		if c.distribution {
+			c.printDistribution(os.Stdout, results)
+			return
+		}
+		if err := c.printResults(results); err != nil {
+			klog.Warningf("failed to print results: %v", err)
+		}
+		return
+	}
+	if c.action == "orphans" {
+		results, err := c.FindOrphanedKeys()
+		if c.prune && len(results) != 0 {
+			klog.Infoln(summarize(results))
+		}
+		c.saveSummary(results, err)
+		if err := c.printResults(results); err != nil {
+			klog.Warningf("failed to print results: %v", err)
+		}
+		if err != nil {
+			klog.Errorf("Errors occurred: %v\n", err)
+			klog.Flush()
+			os.Exit(c.errorExitCode())
+		}
+		if len(results) == 0 {
+			c.succeed("no orphaned storageclass quota keys found.")
+		}
+		return
+	}
+	if c.action == "check" {
+		results, err := c.CheckAgainstBaseline()
+		if len(results) != 0 {
+			klog.Infof("%d storageclass quotas checked against baseline", len(results))
+		}
+		c.saveSummary(results, err)
+		if err := c.printResults(results); err != nil {
+			klog.Warningf("failed to print results: %v", err)
+		}
+		errorCode, driftCode := c.errorExitCode(), exitCodeDrift
+		if c.diffExitCode {
+			driftCode = diffExitCodeDrift
+		}
+		if err != nil {
+			klog.Errorf("Errors occurred: %v\n", err)
+			klog.Flush()
+			os.Exit(errorCode)
+		}
+		if n := countDrifted(results); n != 0 {
+			klog.Warningf("%d storageclass quotas differ from baseline %s", n, c.baselineFile)
+			klog.Flush()
+			os.Exit(driftCode)
+		}
+		c.succeed("all storageclass quotas match the baseline.")
+		return
+	}
 
-	patchDeleteTemplate = `{
-		"spec": {
-			"hard": {
-				"%s.storageclass.storage.k8s.io/requests.storage": null
-			}
+	if c.countOnly {
+		counts, err := c.CountScope()
+		if err != nil {
+			klog.Errorf("Errors occurred: %v\n", err)
+			klog.Flush()
+			os.Exit(exitCodeError)
 		}
-	
-	}`
-)
+		fmt.Println(counts)
+		return
+	}
 
-func main() {
-	var errorList []error
-	c := NewConfig()
-	if err := c.PatchStorageclassRestricted(); err != nil {
+	if c.timing {
+		c.timings = newTimings()
+	}
+	results, err := c.PatchStorageclassRestricted()
+	if err != nil {
 		errorList = append(errorList, err)
 	}
+	if err != nil && c.transactional && len(c.journal) != 0 {
+		if rollbackErr := c.rollback(results); rollbackErr != nil {
+			klog.Errorf("rollback incomplete, some resourcequotas keep the new limits: %v", rollbackErr)
+			errorList = append(errorList, rollbackErr)
+		} else {
+			klog.Warningf("rollback complete, every patched resourcequota has its previous limits again")
+		}
+	}
+	if c.reconcileAfterRun && !c.dryRun {
+		if err := c.reconcile(results); err != nil {
+			errorList = append(errorList, err)
+		}
+	}
+	if len(results) != 0 {
+		klog.Infoln(summarize(results))
+	}
+	if c.dryRun && len(results) != 0 {
+		klog.Infof("[dry-run] %s", plannedTotals(results))
+	}
+	c.saveSummary(results, err)
+	if err := c.printResults(results); err != nil {
+		klog.Warningf("failed to print results: %v", err)
+	}
+	if c.timings != nil {
+		klog.Infof("timing: %v", c.timings)
+	}
+
+	// An empty scope usually surfaces as a "no ResourceQuota found" error of the list.
+	if c.failIfNoQuotas && len(results) == 0 {
+		if len(errorList) != 0 {
+			klog.Errorf("Errors occurred: %v\n", utilerrors.NewAggregate(errorList))
+		}
+		klog.Errorf("no resourcequota left to process in namespace/%s after filtering", c.namespace)
+		klog.Flush()
+		os.Exit(exitCodeError)
+	}
 
 	if len(errorList) == 0 {
-		klog.Infoln("\033[32msuccessfully added or removed storageclass restrictions for all namespaces.\033[0m")
+		c.succeed("successfully added or removed storageclass restrictions for all namespaces.")
 		return
-	} else {
-		aggregatedError := utilerrors.NewAggregate(errorList)
-		klog.Infof("Errors occurred: %v\n", aggregatedError)
 	}
+	// Failed patches, an aborted --exit-on-first-error run and reconcile mismatches
+	// all end here.
+	aggregatedError := utilerrors.NewAggregate(errorList)
+	klog.Errorf("Errors occurred: %v\n", aggregatedError)
+	klog.Flush()
+	os.Exit(c.errorExitCode())
 }
 
-func NewConfig() *Config {
-	config := new(Config)
-	pflag.StringVarP(&config.storageclass, "storageclass", "s", "", "specify the storage class you want to restrict usage of..")
-	pflag.StringVarP(&config.action, "action", "a", "add", "specify the action you want to take (add or remove restriction; the default action is add).")
-	pflag.StringVarP(&config.namespace, "namespace", "n", "", "specify the namespace(default to all namespace.)")
-	pflag.StringVarP(&config.size, "quota", "q", "0", "specify the size of usage of storageclass.(for example 50G | 200T,default to 0 represent disable)")
+// succeed logs the green success message of the run, or --success-message instead.
+func (c *Config) succeed(message string) {
+	if c.successMessage != "" {
+		message = c.successMessage
+	}
+	klog.Infof("\033[32m%s\033[0m", message)
+}
 
-	klog.InitFlags(nil)
-	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
-	pflag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s -s <size> -q <quota> -n <namespace> -a [add|remove] \n", os.Args[0])
-		fmt.Fprintln(os.Stderr, "  举例: ")
-		fmt.Fprintf(os.Stderr, "  	禁用prometheus对rbd-ceph-csi的使用  %s -s rbd-ceph-csi -a add -n prometheus \n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  	允许prometheus对rbd-ceph-csi的使用  %s -s rbd-ceph-csi -a remove -n prometheus \n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  	禁用所有命名空间对rbd-ceph-csi的使用  %s -s rbd-ceph-csi -a add \n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  	将prometheus命名空间对rbd-ceph-csi的限额调整为50G  %s -s rbd-ceph-csi -a add -n prometheus -q 50G \n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "\nOptions:\n")
-		pflag.PrintDefaults()
+// saveSummary writes the --summary-json file and the --result-configmap and
+// posts it to --notify-url if requested, failures are only logged. With
+// --output-redacted the namespaces are redacted once for all of them, in the
+// results as well as in the error.
+func (c *Config) saveSummary(results []Result, err error) {
+	if c.summaryFile == "" && c.resultConfigMap == "" && c.notifyURL == "" {
+		return
+	}
+	errText := ""
+	if err != nil {
+		errText = err.Error()
+	}
+	if c.redacted {
+		extra := namespacesIn(errText)
+		if c.namespace != metav1.NamespaceAll {
+			extra = append(extra, c.namespace)
+		}
+		var redactErr error
+		if results, redactErr = c.redact(results, extra...); redactErr != nil {
+			klog.Warningf("failed to redact the summary, it is not saved: %v", redactErr)
+			return
+		}
+		errText = c.redactText(errText)
+	}
+	summary := c.newSummary(results, errText)
+
+	if c.summaryFile != "" {
+		if err := writeSummaryJSON(c.summaryFile, summary); err != nil {
+			klog.Warningf("failed to write summary to %s: %v", c.summaryFile, err)
+		}
+	}
+	if c.resultConfigMap != "" {
+		if err := c.writeResultConfigMap(summary); err != nil {
+			klog.Warningf("failed to write summary to configmap %s: %v", c.resultConfigMap, err)
+		}
+	}
+	if c.notifyURL != "" {
+		if err := c.notify(summary); err != nil {
+			klog.Warningf("failed to notify %s: %v", c.notifyURL, err)
+		}
+	}
+}
+
+// AddFlags registers the flags shared by every action on fs: the scope,
+// the connection to the apiserver and the logging.
+func (config *Config) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&config.namespace, "namespace", "n", "", "specify the namespace(default to all namespace.)")
+	fs.StringVar(&config.target, "target", "", "only process the single resourcequota given as namespace/name.")
+	fs.StringSliceVar(&config.namespaces, "namespaces", nil, "comma separated namespaces to list resourcequotas from one by one, for identities that cannot list them cluster-wide.")
+	fs.StringVar(&config.namespacesFile, "namespaces-file", "", "file with one namespace per line, added to --namespaces.")
+	fs.StringVar(&config.namespacePrefix, "namespace-prefix", "", "only process namespaces whose name starts with this prefix, for example tenant-.")
+	fs.StringArrayVar(&config.excludeNamespaces, "exclude-namespace", nil, "skip namespaces matching this glob pattern, this flag can be repeated.")
+	fs.StringVar(&config.quotaSelector, "quota-selector", "", "only list the resourcequotas matching this label selector (for example storage-governing=true).")
+	fs.StringVar(&config.platform, "platform", "kubernetes", "specify the platform (kubernetes or openshift), openshift excludes openshift-*, kube-* and default.")
+	fs.StringVar(&config.keyFormat, "quota-key-format", defaultQuotaKeyFormat, "specify the printf-style template of the quota key, the first %s is the storageclass name and the second is the resource suffix.")
+	fs.StringVar(&config.storageclassAPIVersion, "storageclass-api-version", defaultStorageclassAPIVersion, "the group of the quota key between the storageclass name and the resource suffix, for clusters that serve storage classes from a newer group.")
+	fs.StringVar(&config.sortBy, "sort", "name", "specify the order in which resourcequotas are processed (name, created or none).")
+	fs.StringVar(&config.resumeFrom, "resume-from", "", "skip all namespaces sorted lexically before the given one, used to continue an interrupted run.")
+	fs.StringVar(&config.asUser, "as", "", "username to impersonate for the operation.")
+	fs.StringArrayVar(&config.asGroups, "as-group", nil, "group to impersonate for the operation, this flag can be repeated to specify multiple groups.")
+	fs.StringVar(&config.asUID, "as-uid", "", "uid to impersonate for the operation.")
+	fs.BoolVar(&config.skipRBAC, "skip-rbac-check", false, "skip the pre-run permission self-check.")
+	fs.StringVar(&config.lang, "lang", "zh", "language of the usage text (zh or en).")
+	fs.Int64Var(&config.listLimit, "list-limit", 0, "list resourcequotas in pages of this size and process each page before fetching the next (0 lists everything at once).")
+	fs.StringVar(&config.sinceResourceVersion, "since-resource-version", "", "only process the resourcequotas changed after this resourceVersion, falls back to all of them when it is too old.")
+	fs.BoolVar(&config.quiet, "quiet", false, "only log warnings and errors, the --output results still go to stdout.")
+	fs.StringVar(&config.successMessage, "success-message", "", "replace the message logged when the run succeeds.")
+	fs.DurationVar(&config.timeout, "timeout", 0, "abort the whole run after this duration (0 means no limit).")
+	fs.DurationVar(&config.perRequestTimeout, "per-request-timeout", 0, "abort any single api request after this duration (0 means no limit).")
+	fs.StringVar(&config.effort, "effort", "", "preset of retries, per-request-timeout, qps and burst (low, normal or high), explicit flags override it.")
+	fs.IntVar(&config.retries, "retries", 3, "how many times a storageclass get or a patch is retried on transient errors.")
+	fs.Float32Var(&config.qps, "qps", 0, "maximum queries per second to the apiserver (0 keeps the client default).")
+	fs.IntVar(&config.burst, "burst", 0, "maximum burst of queries to the apiserver (0 keeps the client default).")
+	fs.StringVarP(&config.output, "output", "o", "", "output format of the results (text, table, wide or json), wide adds the provisioner and volumeBindingMode of each storage class, defaults to table on a terminal and text otherwise.")
+	fs.StringVar(&config.configFile, "config", "", "YAML file whose keys are flag names, used as defaults that command-line flags override.")
+	fs.BoolVar(&config.dynamic, "dynamic", false, "patch resourcequotas through the dynamic client with unstructured objects instead of the typed client.")
+	fs.StringVar(&config.dynamicResource, "dynamic-resource", "resourcequotas.v1.", "resource read and patched by --dynamic, given as resource.version.group, for example quotas.v1alpha1.example.com.")
+	fs.BoolVar(&config.strictVersion, "strict-version", false, "exit instead of warning when the cluster version is outside the tested range.")
+}
+
+// AddActionFlag registers the deprecated --action flag of the root command on fs.
+func (config *Config) AddActionFlag(fs *pflag.FlagSet) {
+	fs.StringVarP(&config.action, "action", "a", "add", "deprecated, use the subcommands instead. specify the action you want to take (add, remove, lint or check; the default action is add).")
+}
+
+// AddStorageclassFlags registers the flags that select the storage classes on fs.
+func (config *Config) AddStorageclassFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&config.storageclass, "storageclass", "s", "", "specify the storage class you want to restrict usage of..")
+	fs.StringVar(&config.storageclassPattern, "storageclass-pattern", "", "restrict every storage class whose name matches this glob pattern (for example rbd-*) instead of a single one.")
+	fs.BoolVar(&config.skipSCCheck, "skip-sc-check", false, "do not check that --storageclass exists, for storage classes created in the same rollout.")
+	fs.BoolVar(&config.printStorageclass, "print-storageclass", false, "print the provisioner, reclaim policy, volume binding mode and parameters of the resolved storage classes as JSON.")
+}
+
+// AddValueFlags registers the flags that give the quota value of add on fs.
+func (config *Config) AddValueFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&config.size, "quota", "q", "0", "specify the size of usage of storageclass.(for example 50G | 200T,default to 0 represent disable)")
+	fs.BoolVar(&config.onlyIfUnused, "only-if-unused", false, "with add --quota 0, skip the namespaces that still have persistentvolumeclaims of the storageclass unless --force.")
+	fs.StringVar(&config.sourceKey, "source-key", "", "take the quota value from this existing spec.hard key of each resourcequota instead of --quota, for example --source-key=requests.storage.")
+	fs.StringVar(&config.deltaValue, "delta", "", "add this amount (for example +50Gi or -10Gi) to the current storageclass quota instead of setting --quota.")
+	fs.BoolVar(&config.assumeZero, "assume-zero", false, "with --delta, treat a missing storageclass quota as 0 instead of skipping the resourcequota, and with --size-percent a missing requests.storage.")
+	fs.Float64Var(&config.sizePercent, "size-percent", 0, "set each storageclass quota to this percentage of the requests.storage quota of the same resourcequota instead of --quota.")
+}
+
+// AddLimitFlags registers the flags that bound the quota values written on fs.
+func (config *Config) AddLimitFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&config.minValue, "min-value", "", "raise any quota value below this floor up to it.(for example 10Gi)")
+	fs.StringVar(&config.maxValue, "max-value", "", "lower any quota value above this ceiling down to it.(for example 1Ti)")
+	fs.StringVar(&config.sanityMaxValue, "sanity-max", "1Pi", "refuse to set any quota above this value unless --force is given, to catch unit mistakes.")
+	fs.BoolVar(&config.increaseOnly, "increase-only", false, "only raise quotas, skip any resourcequota where the new value would be lower than the current one.")
+	fs.BoolVar(&config.requireBinarySI, "require-binary-si", false, "reject quota values that are not in binary SI units (Ki, Mi, Gi, ...).")
+	fs.BoolVar(&config.safe, "safe", false, "skip any resourcequota where the new value would be below what is already used, instead of only warning.")
+}
+
+// AddPatchFlags registers the flags of the actions that patch resourcequotas on fs.
+func (config *Config) AddPatchFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&config.checkpointFile, "checkpoint-file", "", "record processed resourcequotas in this file and skip them when the run is restarted.")
+	fs.BoolVar(&config.ignoreCheckpoint, "ignore-checkpoint", false, "discard the content of an existing checkpoint file.")
+	fs.BoolVar(&config.onlyWithPVCs, "only-with-pvcs", false, "only process namespaces that have persistentvolumeclaims of the storageclass.")
+	fs.BoolVar(&config.annotateManaged, "annotate-managed", false, "record the tool, action and time as annotations on every patched resourcequota.")
+	fs.StringVar(&config.labelManaged, "label-managed", "", "merge this key=value label into every patched resourcequota, to find them with kubectl get -l.")
+	fs.BoolVar(&config.annotationsOnly, "patch-annotations-only", false, "only write the management annotations without changing the hard limits, used to validate permissions.")
+	fs.BoolVar(&config.timing, "timing", false, "print patch latency statistics and the total duration at the end of the run.")
+	fs.BoolVar(&config.exitOnFirstError, "exit-on-first-error", false, "stop processing at the first failed resourcequota instead of continuing with the rest.")
+	fs.BoolVar(&config.countOnly, "count-only", false, "only print how many namespaces and resourcequotas are in scope and how many already match the target, then exit.")
+	fs.BoolVar(&config.reconcileAfterRun, "reconcile", false, "once the run is over, get every patched resourcequota again and fail if its storageclass quota no longer has the applied value.")
+	fs.BoolVar(&config.transactional, "transactional", false, "with --exit-on-first-error, revert the resourcequotas already patched in this run when it aborts.")
+	fs.BoolVar(&config.failIfNoQuotas, "fail-if-no-quotas", false, "exit with 1 when no resourcequota is left to process after filtering.")
+	fs.BoolVar(&config.printKubectl, "print-kubectl", false, "print the equivalent kubectl patch command of every change to stdout, also in dry-run.")
+	fs.StringVar(&config.allowedWindow, "allowed-window", "", "only patch within this local time window, given as [days] HH:MM-HH:MM (for example Mon-Fri 22:00-02:00), unless --force is given.")
+	fs.BoolVar(&config.optimistic, "optimistic", false, "make every patch conditional on the resourceVersion of the listed resourcequota and retry with a fresh copy on conflicts.")
+	fs.BoolVar(&config.checkOwnership, "check-ownership", false, "warn when the quota keys to patch are owned by another field manager.")
+	fs.BoolVar(&config.dryRun, "dry-run", false, "only print the changes that would be made without patching anything.")
+	fs.StringVar(&config.patchType, "patch-type", "strategic", "type of patch sent to the apiserver (strategic, merge or json), merge is the default with --dynamic as custom resources do not support strategic.")
+	fs.StringVar(&config.groupByLabel, "group-by-label", "", "process namespaces grouped by the value of this namespace label, one group at a time.")
+	fs.DurationVar(&config.pauseBetween, "pause-between", 0, "wait this long between the patches of two namespaces, ignored in dry-run.")
+	fs.DurationVar(&config.groupPause, "group-pause", 0, "pause between two namespace groups of --group-by-label.")
+	fs.BoolVar(&config.enableLeaderElection, "enable-leader-election", false, "only patch once this replica holds the lease, for running several replicas.")
+	fs.StringVar(&config.leaseName, "lease-name", "storageclass-restrict", "name of the lease used by --enable-leader-election.")
+	fs.StringVar(&config.leaseNamespace, "lease-namespace", "default", "namespace of the lease used by --enable-leader-election.")
+	fs.DurationVar(&config.leaseDuration, "lease-duration", 15*time.Second, "how long a lease is valid before another replica may take it over.")
+	fs.DurationVar(&config.renewDeadline, "renew-deadline", 10*time.Second, "how long the leader keeps retrying to renew the lease before giving it up.")
+	fs.DurationVar(&config.retryPeriod, "retry-period", 2*time.Second, "how long to wait between two attempts to acquire or renew the lease.")
+	fs.StringVar(&config.runID, "run-id", "", "identifies one run shared by all the replicas with --enable-leader-election, only the first replica to hold the lease runs it. Without it a replica skips the run when another one claimed the lease after this replica started.")
+	fs.BoolVar(&config.force, "force", false, "re-apply the patch even if the resourcequota is already at the target value.")
+}
+
+// AddSummaryFlags registers the flags that control the results and the summary of a run on fs.
+func (config *Config) AddSummaryFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&config.summaryFile, "summary-json", "", "write a JSON summary with counts and per-namespace outcomes to this file at the end of the run.")
+	fs.StringVar(&config.notifyURL, "notify-url", "", "POST the JSON summary of the run to this URL when it finishes, a failure is only logged.")
+	fs.DurationVar(&config.notifyTimeout, "notify-timeout", 10*time.Second, "timeout of one --notify-url request.")
+	fs.IntVar(&config.notifyRetries, "notify-retries", 2, "how many times a failed --notify-url request is retried.")
+	fs.BoolVar(&config.redacted, "output-redacted", false, "replace namespace names with ns-0001, ns-0002, ... in the results on stdout and in the summary file.")
+	fs.StringVar(&config.redactionMap, "redaction-map", "", "write the mapping of --output-redacted aliases to namespace names to this file.")
+	fs.BoolVar(&config.relativeChange, "output-relative-change", false, "add the relative change between the old and new value (for example +20%) to the table and json output.")
+	fs.StringVar(&config.outputSort, "output-sort", "status", "order of the results in the output and the summary (status, namespace or value), status lists failures first.")
+	fs.StringVar(&config.resultConfigMap, "result-configmap", "", "store the JSON summary of the run in this configmap, given as namespace/name.")
+	fs.StringVar(&config.reason, "reason", "", "why the run happens, recorded in the management annotations, the events of the patched resourcequotas and the summary, implies --annotate-managed.")
+	fs.StringVar(&config.ticket, "ticket", "", "change ticket the run belongs to, recorded like --reason, implies --annotate-managed.")
+}
+
+// AddSyncFlags registers the flags of sync on fs.
+func (config *Config) AddSyncFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&config.templateNamespace, "template-namespace", "", "namespace whose storageclass quotas the sync action applies to every other namespace.")
+}
+
+// AddCreateFlags registers the flags of sync and import that create missing resourcequotas on fs.
+func (config *Config) AddCreateFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&config.createIfMissing, "create-if-missing", false, "with sync or import, create a resourcequota in namespaces that have none.")
+}
+
+// AddImportFlags registers the flags of import on fs.
+func (config *Config) AddImportFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&config.inputFile, "input-file", "", "with import, the file written by export whose storageclass quotas are applied.")
+}
+
+// AddExportFlags registers the flags of export on fs.
+func (config *Config) AddExportFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&config.outputFile, "output-file", "", "with export, write the storageclass quotas to this file instead of stdout, as YAML when it ends with .yaml or .yml and JSON otherwise.")
+}
+
+// AddCheckFlags registers the flags of check on fs.
+func (config *Config) AddCheckFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&config.baselineFile, "report-diff-against-file", "", "JSON or YAML file of expected quotas ({\"namespace\": {\"storageclass\": \"50Gi\"}}), as written by export, that the check action compares the cluster against.")
+	fs.BoolVar(&config.diffExitCode, "diff-exit-code", false, "with check, exit with 0 when nothing drifted, 1 on drift and 2 on errors, like git diff --exit-code.")
+}
+
+// AddReportFlags registers the flags of report on fs.
+func (config *Config) AddReportFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&config.distribution, "distribution", false, "with report, print a histogram of the storageclass quotas across namespaces instead of every value.")
+}
+
+// AddOrphansFlags registers the flags of orphans on fs.
+func (config *Config) AddOrphansFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&config.prune, "prune", false, "with orphans, remove the quota keys of storage classes that do not exist.")
+}
+
+// Complete validates the parsed flags of fs and builds the kubernetes client.
+// Invalid input and setup failures exit the process.
+func (config *Config) Complete(fs *pflag.FlagSet) {
+	config.startedAt = time.Now()
+	if config.configFile != "" {
+		if err := applyConfigFile(fs, config.configFile); err != nil {
+			exitUsage("%v", err)
+		}
+	}
+
+	if config.quiet {
+		quietLogs()
+	}
+
+	var errs []error
+	if config.effort != "" {
+		if err := config.applyEffort(fs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if config.retries < 0 {
+		errs = append(errs, fmt.Errorf("retries must not be negative,and you provide %d", config.retries))
+	}
+
+	if _, ok := usageTexts[config.lang]; !ok {
+		errs = append(errs, fmt.Errorf("lang must be zh or en,and you provide %s", config.lang))
+	}
+
+	if config.target != "" {
+		namespace, name, ok := parseNamespacedName(config.target)
+		if !ok {
+			errs = append(errs, fmt.Errorf("target must be in the form namespace/name,and you provide %s", config.target))
+		} else if config.namespace != "" && config.namespace != namespace {
+			errs = append(errs, fmt.Errorf("target %s is not in namespace/%s", config.target, config.namespace))
+		} else {
+			config.namespace, config.targetNamespace, config.targetName = namespace, namespace, name
+		}
+	}
+
+	if config.resultConfigMap != "" {
+		if _, _, ok := parseNamespacedName(config.resultConfigMap); !ok {
+			errs = append(errs, fmt.Errorf("result-configmap must be in the form namespace/name,and you provide %s", config.resultConfigMap))
+		}
+	}
+
+	if config.namespacesFile != "" {
+		namespaces, err := readNamespacesFile(config.namespacesFile)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		config.namespaces = append(config.namespaces, namespaces...)
+	}
+	if len(config.namespaces) != 0 && (config.namespace != "" || config.target != "") {
+		errs = append(errs, errors.New("namespaces and namespaces-file cannot be combined with namespace or target"))
+	}
+	if config.labelManaged != "" {
+		key, value, ok := strings.Cut(config.labelManaged, "=")
+		if !ok {
+			errs = append(errs, fmt.Errorf("label-managed must be in the form key=value,and you provide %s", config.labelManaged))
+		} else if msgs := append(validation.IsQualifiedName(key), validation.IsValidLabelValue(value)...); len(msgs) != 0 {
+			errs = append(errs, fmt.Errorf("invalid label-managed %q: %s", config.labelManaged, strings.Join(msgs, "; ")))
+		} else {
+			config.labelKey, config.labelValue = key, value
+		}
+	}
+
+	// The attribution is only written through the management annotations.
+	if config.reason != "" || config.ticket != "" {
+		config.annotateManaged = true
+	}
+
+	if config.redactionMap != "" && !config.redacted {
+		errs = append(errs, errors.New("redaction-map requires --output-redacted"))
+	}
+	if config.countOnly && config.action != "add" && config.action != "remove" && config.action != "sync" {
+		errs = append(errs, fmt.Errorf("count-only only applies to add, remove and sync,and you provide %s", config.action))
+	}
+	if config.transactional && !config.exitOnFirstError {
+		errs = append(errs, errors.New("transactional requires --exit-on-first-error"))
+	}
+	if config.transactional && config.checkpointFile != "" {
+		// A restart from the checkpoint would skip the resourcequotas that were rolled back.
+		errs = append(errs, errors.New("transactional cannot be combined with checkpoint-file"))
+	}
+	if len(config.namespaces) != 0 && config.sinceResourceVersion != "" {
+		errs = append(errs, errors.New("namespaces and namespaces-file cannot be combined with since-resource-version"))
 	}
-	pflag.Parse()
 
 	if config.namespace == "" {
 		config.namespace = metav1.NamespaceAll
 	}
 
-	if config.storageclass == "" {
-		klog.Exitln("storageclass is empty,please specify storageclass")
+	if config.storageclass != "" && config.storageclassPattern != "" {
+		errs = append(errs, errors.New("storageclass and storageclass-pattern are mutually exclusive"))
+	}
+
+	if config.storageclassPattern != "" {
+		if _, err := path.Match(config.storageclassPattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid storageclass-pattern %q: %v", config.storageclassPattern, err))
+		}
+	} else if config.storageclass == "" && (config.mutates() || config.action == "report") && config.action != "sync" && config.action != "import" && config.action != "orphans" {
+		errs = append(errs, errors.New("storageclass is empty,please specify storageclass"))
+	}
+
+	if err := config.ParseSize(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := config.ParseBounds(); err != nil {
+		errs = append(errs, err.(utilerrors.Aggregate).Errors()...)
+	}
+
+	if config.delta != nil && (config.sourceKey != "" || fs.Changed("quota")) {
+		errs = append(errs, errors.New("delta cannot be combined with quota or source-key"))
+	}
+	if fs.Changed("size-percent") && (!(config.sizePercent > 0) || math.IsInf(config.sizePercent, 0)) {
+		errs = append(errs, fmt.Errorf("size-percent must be greater than 0,and you provide %v", config.sizePercent))
+	}
+	if fs.Changed("size-percent") && (config.delta != nil || config.sourceKey != "" || fs.Changed("quota")) {
+		errs = append(errs, errors.New("size-percent cannot be combined with quota, source-key or delta"))
+	}
+
+	if fs.Changed("storageclass-api-version") {
+		switch {
+		case fs.Changed("quota-key-format"):
+			errs = append(errs, errors.New("storageclass-api-version and quota-key-format are mutually exclusive"))
+		case config.storageclassAPIVersion == "" || strings.ContainsAny(config.storageclassAPIVersion, "%/"):
+			errs = append(errs, fmt.Errorf("storageclass-api-version must be a non-empty group without %% or /,and you provide %q", config.storageclassAPIVersion))
+		default:
+			config.keyFormat = quotaKeyFormatFor(config.storageclassAPIVersion)
+		}
+	}
+	if n := countFormatVerbs(config.keyFormat); n != 2 {
+		errs = append(errs, fmt.Errorf("quota-key-format must contain exactly 2 %%s verbs (storageclass and suffix),and you provide %q with %d", config.keyFormat, n))
+	}
+
+	if config.action != "add" && config.action != "remove" && config.action != "lint" && config.action != "check" && config.action != "list-storageclasses" && config.action != "audit-missing" && config.action != "sync" && config.action != "report" && config.action != "orphans" && config.action != "export" && config.action != "import" {
+		errs = append(errs, fmt.Errorf("action must be add, remove, sync, lint, check, report, orphans, export, import, list-storageclasses or audit-missing,and you provide %s", config.action))
+	}
+
+	if config.action == "check" && config.baselineFile == "" {
+		errs = append(errs, errors.New("check requires --report-diff-against-file"))
+	}
+
+	if config.action == "import" && config.inputFile == "" {
+		errs = append(errs, errors.New("import requires --input-file"))
+	}
+
+	if config.action == "sync" && config.templateNamespace == "" {
+		errs = append(errs, errors.New("sync requires --template-namespace"))
+	}
+
+	if config.action == "sync" && (config.storageclass != "" || config.storageclassPattern != "") {
+		errs = append(errs, errors.New("sync takes the storageclasses from --template-namespace and cannot be combined with storageclass or storageclass-pattern"))
+	}
+
+	if config.sortBy != "name" && config.sortBy != "created" && config.sortBy != "none" {
+		errs = append(errs, fmt.Errorf("sort must be name, created or none,and you provide %s", config.sortBy))
+	}
+
+	if _, ok := platformExcludedNamespaces[config.platform]; !ok {
+		errs = append(errs, fmt.Errorf("platform must be kubernetes or openshift,and you provide %s", config.platform))
+	}
+
+	if config.quotaSelector != "" {
+		if _, err := labels.Parse(config.quotaSelector); err != nil {
+			errs = append(errs, fmt.Errorf("invalid quota-selector %q: %v", config.quotaSelector, err))
+		}
+	}
+	for _, pattern := range config.excludeNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid exclude-namespace pattern %q: %v", pattern, err))
+		}
+	}
+
+	switch config.output {
+	case "":
+		config.output = defaultOutput()
+	case outputText, outputTable, outputWide, outputJSON:
+	default:
+		errs = append(errs, fmt.Errorf("output must be text, table, wide or json,and you provide %s", config.output))
+	}
+
+	var window *window
+	if config.allowedWindow != "" {
+		w, err := parseWindow(config.allowedWindow)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid allowed-window %q: %v", config.allowedWindow, err))
+		}
+		window = w
+	}
+
+	if config.outputSort != "status" && config.outputSort != "namespace" && config.outputSort != "value" {
+		errs = append(errs, fmt.Errorf("output-sort must be status, namespace or value,and you provide %s", config.outputSort))
 	}
 
-	config.ParseSize()
+	if _, ok := patchTypes[config.patchType]; !ok {
+		errs = append(errs, fmt.Errorf("patch-type must be strategic, merge or json,and you provide %s", config.patchType))
+	}
+
+	if config.listLimit < 0 {
+		errs = append(errs, fmt.Errorf("list-limit must not be negative,and you provide %d", config.listLimit))
+	}
+
+	if config.notifyRetries < 0 {
+		errs = append(errs, fmt.Errorf("notify-retries must not be negative,and you provide %d", config.notifyRetries))
+	}
+
+	if config.groupByLabel != "" && config.listLimit != 0 {
+		errs = append(errs, errors.New("group-by-label cannot be combined with list-limit"))
+	}
 
-	config.context = context.TODO()
-	if config.action != "add" && config.action != "remove" {
-		klog.Exitf("action must be add or remove,and you provide %s", config.action)
+	if config.enableLeaderElection && (config.leaseDuration <= config.renewDeadline || config.renewDeadline <= config.retryPeriod) {
+		errs = append(errs, fmt.Errorf("lease-duration must be greater than renew-deadline and renew-deadline greater than retry-period,and you provide %v, %v and %v", config.leaseDuration, config.renewDeadline, config.retryPeriod))
+	}
+
+	if config.resumeFrom != "" && config.sortBy != "name" {
+		errs = append(errs, fmt.Errorf("resume-from requires --sort=name,and you provide %s", config.sortBy))
+	}
+
+	if gvr, _ := schema.ParseResourceArg(config.dynamicResource); gvr == nil || gvr.Resource == "" || gvr.Version == "" {
+		errs = append(errs, fmt.Errorf("dynamic-resource must be in the form resource.version.group,and you provide %s", config.dynamicResource))
+	} else {
+		config.quotaResource = *gvr
+	}
+	if fs.Changed("dynamic-resource") && !config.dynamic {
+		errs = append(errs, errors.New("dynamic-resource requires --dynamic"))
+	}
+	if config.createIfMissing && config.quotaResource != resourceQuotaGVR {
+		errs = append(errs, errors.New("create-if-missing cannot be combined with a dynamic-resource other than resourcequotas.v1."))
+	}
+	if config.dynamic && !fs.Changed("patch-type") {
+		config.patchType = "merge"
+	}
+	if config.patchType == "strategic" && !config.quotaResource.Empty() && config.quotaResource != resourceQuotaGVR {
+		errs = append(errs, fmt.Errorf("patch-type strategic is not supported by custom resources,and you provide dynamic-resource %s", config.dynamicResource))
+	}
+
+	if len(errs) != 0 {
+		exitUsage("invalid flags:\n%s", formatErrors(errs))
+	}
+
+	if window != nil {
+		now := time.Now()
+		switch {
+		case !config.mutates() || config.dryRun || config.countOnly:
+		case window.contains(now):
+			klog.V(2).Infof("%s is within the allowed window %s", now.Format("Mon 15:04"), config.allowedWindow)
+		case config.force:
+			klog.Warningf("%s is outside the allowed window %s, continue because of --force", now.Format("Mon 15:04"), config.allowedWindow)
+		default:
+			config.fatalf("%s is outside the allowed window %s (use --force to override)", now.Format("Mon 15:04"), config.allowedWindow)
+		}
+	}
+
+	config.context, config.cancel = context.WithCancel(context.Background())
+	if config.timeout > 0 {
+		config.context, config.cancel = context.WithTimeout(context.Background(), config.timeout)
 	}
 
 	c, err := clientcmd.BuildConfigFromFlags("", clientcmd.RecommendedHomeFile)
 	if err != nil {
-		klog.Exitf("error happened when building config,%v\n", err.Error())
+		config.exitWithHint("error happened when building config", err)
+	}
+	if config.impersonating() {
+		if !config.skipRBAC {
+			self, err := kubernetes.NewForConfig(c)
+			if err != nil {
+				config.exitWithHint("error happened when construct kubernetes client", err)
+			}
+			config.CheckImpersonation(self)
+		}
+		config.applyImpersonation(c)
+	}
+	if config.qps > 0 {
+		c.QPS = config.qps
+	}
+	if config.burst > 0 {
+		c.Burst = config.burst
 	}
 	client, err := kubernetes.NewForConfig(c)
 	if err != nil || client == nil {
-		klog.Exitf("error happened when construct kubernetes client,%v\n", err.Error())
+		config.exitWithHint("error happened when construct kubernetes client", err)
+	}
+	info, err := client.Discovery().ServerVersion()
+	if err != nil {
+		config.exitWithHint("error happened when connecting to the apiserver", err)
 	}
 	config.client = client
-	config.CheckIfStorageclassExist()
+	config.host = c.Host
+	config.checkServerVersion(info)
+	if config.dynamic {
+		dynamicClient, err := dynamic.NewForConfig(c)
+		if err != nil {
+			config.exitWithHint("error happened when construct dynamic client", err)
+		}
+		config.dynamicClient = dynamicClient
+	}
+	if !fs.Changed("quota-key-format") && !fs.Changed("storageclass-api-version") {
+		config.discoverQuotaKeyFormat()
+	}
+	if !config.skipRBAC {
+		config.CheckPermissions()
+	}
+	if config.action == "sync" {
+		config.loadTemplate()
+	} else if config.storageclassPattern != "" {
+		config.MatchStorageclasses()
+	} else if config.storageclass != "" {
+		if config.skipSCCheck {
+			klog.Warningf("skip checking that storageclass/%s exists", config.storageclass)
+		} else {
+			config.CheckIfStorageclassExist()
+		}
+		config.storageclasses = []string{config.storageclass}
+	}
+	if config.action == "import" {
+		config.loadImport()
+	}
+}
 
-	return config
+// formatErrors renders one validation error per line.
+func formatErrors(errs []error) string {
+	lines := make([]string, len(errs))
+	for i, err := range errs {
+		lines[i] = "  - " + err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mutates reports whether the action patches ResourceQuotas.
+func (c *Config) mutates() bool {
+	return c.action == "add" || c.action == "remove" || c.action == "sync" || c.action == "import" || (c.action == "orphans" && c.prune)
 }
 
 func (c *Config) CheckIfStorageclassExist() {
-	_, err := c.client.StorageV1().StorageClasses().Get(c.context, c.storageclass, metav1.GetOptions{})
+	var sc *storagev1.StorageClass
+	err := retry.OnError(c.backoff(), isTransient, func() error {
+		ctx, cancel := c.requestContext()
+		defer cancel()
+		var err error
+		sc, err = c.client.StorageV1().StorageClasses().Get(ctx, c.storageclass, metav1.GetOptions{})
+		c.checkRequestTimeout(ctx, "get storageclass/"+c.storageclass)
+		if err != nil && isTransient(err) {
+			klog.Warningf("get storageclass/%s failed, retrying: %v", c.storageclass, err)
+		}
+		return err
+	})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			klog.Exitf("storageclass %s not exist", c.storageclass)
+			c.fatalf("storageclass %s not exist", c.storageclass)
+		}
+		c.fatalf("error happened when get storageclass %s,error: %v", c.storageclass, err.Error())
+	}
+	c.storageclassObjects = map[string]storagev1.StorageClass{sc.Name: *sc}
+	if c.printStorageclass {
+		if err := printStorageclassDetails([]storagev1.StorageClass{*sc}); err != nil {
+			klog.Warningf("failed to print storageclass/%s: %v", c.storageclass, err)
+		}
+	}
+}
+
+// MatchStorageclasses resolves --storageclass-pattern to the names of the matching storage classes.
+func (c *Config) MatchStorageclasses() {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	scs, err := c.client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	c.checkRequestTimeout(ctx, "list storageclasses")
+	if err != nil {
+		c.fatalf("error happened when list storageclasses,error: %v", err.Error())
+	}
+
+	var matched []storagev1.StorageClass
+	c.storageclassObjects = map[string]storagev1.StorageClass{}
+	for _, sc := range scs.Items {
+		if ok, _ := path.Match(c.storageclassPattern, sc.Name); ok {
+			c.storageclasses = append(c.storageclasses, sc.Name)
+			c.storageclassObjects[sc.Name] = sc
+			matched = append(matched, sc)
 		}
-		klog.Exitf("error happened when get storageclass %s,error: %v", c.storageclass, err.Error())
 	}
+	if len(c.storageclasses) == 0 {
+		c.fatalf("no storageclass matches pattern %s", c.storageclassPattern)
+	}
+	if c.printStorageclass {
+		sort.Slice(matched, func(i, j int) bool { return matched[i].Name < matched[j].Name })
+		if err := printStorageclassDetails(matched); err != nil {
+			klog.Warningf("failed to print storageclasses: %v", err)
+		}
+	}
+
+	sort.Strings(c.storageclasses)
+	klog.Infof("storageclass pattern %s matches %s", c.storageclassPattern, strings.Join(c.storageclasses, ","))
 }
 
-func (c *Config) ParseSize() {
+// ParseSize validates and normalizes --quota.
+func (c *Config) ParseSize() error {
 	q, err := resource.ParseQuantity(c.size)
 	if err != nil {
-		klog.Exitf("%v , for example: 50G / 200T", err.Error())
+		return fmt.Errorf("%v , for example: 50G / 200T", err.Error())
 	}
 
 	c.size = q.String()
+	return nil
 }
 
-func (c *Config) PatchStorageclassRestricted() error {
-	var errorList []error
-	rqs, err := c.client.CoreV1().ResourceQuotas(c.namespace).List(c.context, metav1.ListOptions{})
+// ParseBounds parses the quantity flags other than --quota and returns all the
+// problems found in them.
+func (c *Config) ParseBounds() error {
+	var errs []error
+	if c.minValue != "" {
+		q, err := resource.ParseQuantity(c.minValue)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid min-value %v , for example: 10Gi", err.Error()))
+		} else {
+			c.min = &q
+		}
+	}
+	if c.maxValue != "" {
+		q, err := resource.ParseQuantity(c.maxValue)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid max-value %v , for example: 1Ti", err.Error()))
+		} else {
+			c.max = &q
+		}
+	}
+	if c.deltaValue != "" {
+		q, err := resource.ParseQuantity(c.deltaValue)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid delta %v , for example: +50Gi", err.Error()))
+		} else {
+			c.delta = &q
+		}
+	}
+
+	q, err := resource.ParseQuantity(c.sanityMaxValue)
 	if err != nil {
-		return err
+		errs = append(errs, fmt.Errorf("invalid sanity-max %v , for example: 1Pi", err.Error()))
 	}
+	c.sanityMax = q
 
-	if len(rqs.Items) == 0 {
-		return fmt.Errorf("no ResourceQuota found in namespace/%s", c.namespace)
+	if c.min != nil && c.max != nil && c.min.Cmp(*c.max) > 0 {
+		errs = append(errs, fmt.Errorf("min-value %s must not be greater than max-value %s", c.min.String(), c.max.String()))
 	}
 
-	for _, rq := range rqs.Items {
-		var patchData []byte
-		switch c.action {
-		case "add":
-			patchData = []byte(fmt.Sprintf(patchAddTemplate, c.storageclass, c.size))
-		case "remove":
-			patchData = []byte(fmt.Sprintf(patchDeleteTemplate, c.storageclass))
-		default:
+	if c.requireBinarySI {
+		values := map[string]string{"quota": c.size, "min-value": c.minValue, "max-value": c.maxValue, "delta": c.deltaValue}
+		for _, name := range []string{"quota", "min-value", "max-value", "delta"} {
+			q, err := resource.ParseQuantity(values[name])
+			if values[name] == "" || err != nil {
+				continue
+			}
+			if !isBinarySI(q) {
+				errs = append(errs, fmt.Errorf("%s %s is not in binary SI units, for example: 50Gi", name, values[name]))
+			}
 		}
+	}
 
-		patchType := types.StrategicMergePatchType
-		_, err = c.client.CoreV1().ResourceQuotas(rq.Namespace).Patch(c.context, rq.Name, patchType, patchData, metav1.PatchOptions{
-			FieldManager: "storageclass-restriction",
-		})
+	return utilerrors.NewAggregate(errs)
+}
+
+// isBinarySI reports whether q is expressed in binary SI units (Ki, Mi, Gi, ...).
+// Zero carries no unit and is always accepted.
+func isBinarySI(q resource.Quantity) bool {
+	return q.IsZero() || q.Format == resource.BinarySI
+}
+
+// quotaKey returns the requests.storage quota key of class.
+func (c *Config) quotaKey(class string) string {
+	return buildQuotaKey(c.keyFormat, class, requestsStorageSuffix)
+}
+
+// targetFor returns the value the quota key of class should have in rq, or nil if the key should be removed.
+// With --source-key the caller must have checked that rq has the source key.
+func (c *Config) targetFor(rq corev1.ResourceQuota, class string) (*resource.Quantity, error) {
+	if c.action == "sync" {
+		want := c.templateQuotas[class].DeepCopy()
+		return &want, nil
+	}
+	if c.action == "import" {
+		want := resource.MustParse(c.imported[rq.Namespace][class])
+		return &want, nil
+	}
+	if c.action != "add" {
+		return nil, nil
+	}
+
+	want := resource.MustParse(c.size)
+	if c.sourceKey != "" {
+		source := getExistingStorageQuota(rq, c.sourceKey)
+		if source == nil {
+			return nil, fmt.Errorf("resourcequota/%s in namespace/%s has no %s to take the value from", rq.Name, rq.Namespace, c.sourceKey)
+		}
+		want = source.DeepCopy()
+	} else if c.sizePercent > 0 {
+		generic, _ := hardValue(rq, requestsStorageSuffix)
+		scaled, err := quotaArith{}.scale(generic, c.sizePercent/100)
+		if err != nil {
+			return nil, err
+		}
+		want = scaled
+	} else if c.delta != nil {
+		current, _ := hardValue(rq, c.quotaKey(class))
+		sum, err := quotaArith{}.add(current, *c.delta)
+		if err != nil {
+			return nil, err
+		}
+		want = sum
+	}
+	clamped := quotaArith{}.clamp(want, c.min, c.max)
+	if !quantitiesEqual(clamped, want) {
+		klog.Infof("clamp the storageclass/%s limits of namespace/%s from %s to %s within min-value and max-value", class, rq.Namespace, want.String(), clamped.String())
+	}
+
+	return &clamped, nil
+}
+
+// PatchStorageclassRestricted applies the action to every ResourceQuota in scope and
+// returns a Result for each of them along with the aggregated errors.
+func (c *Config) PatchStorageclassRestricted() ([]Result, error) {
+	var errorList []error
+	var results []Result
+
+	var cp *checkpoint
+	if c.checkpointFile != "" && c.dryRun {
+		klog.V(2).Infof("ignore checkpoint file %s in dry-run", c.checkpointFile)
+	} else if c.checkpointFile != "" {
+		var err error
+		cp, err = openCheckpoint(c.checkpointFile, c.ignoreCheckpoint)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := cp.finish(len(errorList) == 0); err != nil {
+				klog.Warningf("failed to finish checkpoint file %s: %v", c.checkpointFile, err)
+			}
+		}()
+	}
+
+	resumeSkipped := 0
+	pvcsInUse := map[string]bool{}
+	process := func(items []corev1.ResourceQuota) error {
+		for _, rq := range items {
+			if c.resumeFrom != "" && rq.Namespace < c.resumeFrom {
+				resumeSkipped++
+				continue
+			}
+
+			if cp.isDone(rq.Namespace, rq.Name) {
+				klog.V(2).Infof("skip namespace/%s, resourcequota/%s is recorded in the checkpoint", rq.Namespace, rq.Name)
+				continue
+			}
+
+			if c.onlyWithPVCs {
+				inUse, ok := pvcsInUse[rq.Namespace]
+				if !ok {
+					var err error
+					inUse, err = c.namespaceHasPVCs(rq.Namespace)
+					if err != nil {
+						klog.Warningf("failed to list persistentvolumeclaims from namespace/%s: %v", rq.Namespace, err)
+						errorList = append(errorList, err)
+						results = append(results, Result{Namespace: rq.Namespace, Quota: rq.Name, Action: c.action, Status: StatusFailed, Message: err.Error()})
+						if c.exitOnFirstError {
+							return err
+						}
+						continue
+					}
+					pvcsInUse[rq.Namespace] = inUse
+				}
+				if !inUse {
+					klog.V(2).Infof("skip namespace/%s, no persistentvolumeclaim uses storageclass/%s", rq.Namespace, strings.Join(c.storageclasses, ","))
+					results = append(results, Result{Namespace: rq.Namespace, Quota: rq.Name, Action: c.action, Status: StatusSkipped, Message: "no persistentvolumeclaim uses the storageclass"})
+					continue
+				}
+			}
+
+			rqResults, err := c.patchResourceQuota(rq)
+			if c.optimistic {
+				retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+					if !apierrors.IsConflict(err) {
+						return nil
+					}
+					klog.Warningf("resourcequota/%s in namespace/%s changed since it was listed, get it again and retry", rq.Name, rq.Namespace)
+					ctx, cancel := c.requestContext()
+					latest, getErr := c.getQuota(ctx, rq.Namespace, rq.Name)
+					c.checkRequestTimeout(ctx, fmt.Sprintf("get resourcequota/%s in namespace/%s", rq.Name, rq.Namespace))
+					cancel()
+					if getErr != nil {
+						return getErr
+					}
+					rqResults, err = c.patchResourceQuota(*latest)
+					return err
+				})
+				if retryErr != nil && !apierrors.IsConflict(retryErr) {
+					err = retryErr
+				}
+			}
+			results = append(results, rqResults...)
+			if err != nil {
+				klog.Warningf("failed to %s the storageclass/%s limits from namespace/%s: %v", c.action, strings.Join(c.storageclasses, ","), rq.Namespace, err)
+				errorList = append(errorList, err)
+				if c.exitOnFirstError {
+					return err
+				}
+				continue
+			}
+			if err := cp.markDone(rq.Namespace, rq.Name); err != nil {
+				klog.Warning(err)
+			}
+		}
+		return nil
+	}
+
+	err := c.forEachResourceQuotaPage(func(items []corev1.ResourceQuota) error {
+		if c.groupByLabel == "" {
+			return process(items)
+		}
+
+		groups, err := c.groupByNamespaceLabel(items)
 		if err != nil {
-			klog.Warningf("failed to %s the storageclass/%s limits from namespace/%s: %v", c.action, c.storageclass, rq.Namespace, err)
+			return err
+		}
+		for i, group := range groups {
+			if i > 0 && c.groupPause > 0 && !c.dryRun {
+				klog.Infof("pause %v before the next namespace group", c.groupPause)
+				select {
+				case <-time.After(c.groupPause):
+				case <-c.context.Done():
+					return c.context.Err()
+				}
+			}
+			klog.Infof("processing namespace group %s=%q with %d resourcequotas", c.groupByLabel, group.value, len(group.items))
+			if err := process(group.items); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if c.resumeFrom != "" {
+		klog.Infof("resume from namespace/%s, skipped %d resourcequotas", c.resumeFrom, resumeSkipped)
+	}
+	if err != nil {
+		if len(errorList) == 0 {
 			errorList = append(errorList, err)
+		}
+		if c.exitOnFirstError {
+			return results, err
+		}
+	}
+
+	if c.action == "sync" && c.createIfMissing {
+		created, err := c.createMissingQuotas()
+		results = append(results, created...)
+		if err != nil {
+			errorList = append(errorList, err)
+		}
+	}
+	if c.action == "import" {
+		created, err := c.createImportedQuotas()
+		results = append(results, created...)
+		if err != nil {
+			errorList = append(errorList, err)
+		}
+	}
+
+	return results, utilerrors.NewAggregate(errorList)
+}
+
+// patchResourceQuota brings the quota key of every target storageclass in rq to its
+// target value with a single patch, and returns one Result per storageclass.
+func (c *Config) patchResourceQuota(rq corev1.ResourceQuota) ([]Result, error) {
+	if len(rq.Spec.Hard) == 0 {
+		klog.V(4).Infof("resourcequota/%s from namespace/%s has no hard limits", rq.Name, rq.Namespace)
+	}
+
+	var results []Result
+	var pending []int
+	var refused []error
+	changes := map[string]*resource.Quantity{}
+	var pvcClasses map[string]int
+	for _, class := range c.storageclasses {
+		if c.action == "import" && !c.importsTo(rq, class) {
+			continue
+		}
+		key := c.quotaKey(class)
+		result := Result{Namespace: rq.Namespace, Quota: rq.Name, StorageClass: class, Action: c.action}
+		warnKeyVariants(rq, key)
+		if c.action == "add" && c.sourceKey != "" && getExistingStorageQuota(rq, c.sourceKey) == nil {
+			klog.V(2).Infof("skip namespace/%s, resourcequota/%s has no %s to take the value from", rq.Namespace, rq.Name, c.sourceKey)
+			result.Status = StatusSkipped
+			result.Message = fmt.Sprintf("source key %s not set", c.sourceKey)
+			results = append(results, result)
+			continue
+		}
+		if source := getExistingStorageQuota(rq, c.sourceKey); c.action == "add" && c.requireBinarySI && source != nil && !isBinarySI(*source) {
+			klog.Warningf("skip namespace/%s, %s of resourcequota/%s is %s which is not in binary SI units", rq.Namespace, c.sourceKey, rq.Name, source.String())
+			result.Status = StatusFailed
+			result.Message = fmt.Sprintf("source value %s is not in binary SI units", source.String())
+			results = append(results, result)
+			refused = append(refused, fmt.Errorf("%s of resourcequota/%s in namespace/%s: %s", c.sourceKey, rq.Name, rq.Namespace, result.Message))
+			continue
+		}
+		if _, ok := hardValue(rq, requestsStorageSuffix); c.action == "add" && c.sizePercent > 0 && !c.assumeZero && !ok {
+			klog.V(2).Infof("skip namespace/%s, resourcequota/%s has no %s to take the percentage of", rq.Namespace, rq.Name, requestsStorageSuffix)
+			result.Status = StatusSkipped
+			result.Message = "no requests.storage quota (use --assume-zero)"
+			results = append(results, result)
 			continue
 		}
-		klog.V(2).Infof("successful %s the storageclass/%s limits from namespace/%s", c.action, c.storageclass, rq.Namespace)
+		if _, ok := hardValue(rq, key); c.action == "add" && c.delta != nil && !c.assumeZero && !ok {
+			klog.V(2).Infof("skip namespace/%s, resourcequota/%s has no storageclass/%s limits to add the delta to", rq.Namespace, rq.Name, class)
+			result.Status = StatusSkipped
+			result.Message = "no current value to add the delta to (use --assume-zero)"
+			results = append(results, result)
+			continue
+		}
+		if existing, ok := hardValue(rq, key); ok {
+			result.Old = existing.String()
+		}
+		want, err := c.targetFor(rq, class)
+		if err != nil {
+			klog.Warningf("skip namespace/%s, cannot compute the storageclass/%s limits of resourcequota/%s: %v", rq.Namespace, class, rq.Name, err)
+			result.Status = StatusFailed
+			result.Message = err.Error()
+			results = append(results, result)
+			refused = append(refused, fmt.Errorf("storageclass/%s limits of namespace/%s: %v", class, rq.Namespace, err))
+			continue
+		}
+		if want != nil {
+			result.New = want.String()
+		}
+
+		if want != nil && want.IsZero() && c.onlyIfUnused && !c.force && !c.annotationsOnly {
+			if pvcClasses == nil {
+				if pvcClasses, err = c.namespacePVCClasses(rq.Namespace); err != nil {
+					klog.Warningf("failed to list persistentvolumeclaims from namespace/%s: %v", rq.Namespace, err)
+					result.Status = StatusFailed
+					result.Message = err.Error()
+					results = append(results, result)
+					refused = append(refused, err)
+					continue
+				}
+			}
+			if n := pvcClasses[class]; n > 0 {
+				klog.Warningf("refuse to zero the storageclass/%s limits of namespace/%s, %d persistentvolumeclaims still use it (use --force to override)", class, rq.Namespace, n)
+				result.Status = StatusSkipped
+				result.Message = fmt.Sprintf("%d persistentvolumeclaims use the storageclass", n)
+				results = append(results, result)
+				continue
+			}
+		}
+
+		if c.annotationsOnly {
+			result.New = result.Old
+		} else if want != nil && want.Sign() < 0 {
+			klog.Warningf("skip namespace/%s, the storageclass/%s limits of resourcequota/%s would go below zero to %s", rq.Namespace, class, rq.Name, want.String())
+			result.Status = StatusSkipped
+			result.Message = "would go below zero"
+			results = append(results, result)
+			continue
+		} else if want != nil && !c.force && want.Cmp(c.sanityMax) > 0 {
+			klog.Warningf("refuse to set the storageclass/%s limits of namespace/%s to %s, it is above sanity-max %s (use --force to override)", class, rq.Namespace, want.String(), c.sanityMax.String())
+			result.Status = StatusFailed
+			result.Message = fmt.Sprintf("%s is above sanity-max %s", want.String(), c.sanityMax.String())
+			results = append(results, result)
+			refused = append(refused, fmt.Errorf("storageclass/%s limits of namespace/%s: %s", class, rq.Namespace, result.Message))
+			continue
+		} else if c.increaseOnly && wouldDecrease(rq, key, want) {
+			klog.Warningf("skip namespace/%s, setting the storageclass/%s limits of resourcequota/%s from %q to %q would decrease it", rq.Namespace, class, rq.Name, result.Old, result.New)
+			result.Status = StatusSkipped
+			result.Message = "would decrease the quota"
+			results = append(results, result)
+			continue
+		} else if used, ok := belowUsed(rq, key, want); ok && c.safe {
+			klog.Warningf("skip namespace/%s, the storageclass/%s limits of resourcequota/%s would be %q which is below the current used %s", rq.Namespace, class, rq.Name, result.New, used.String())
+			result.Status = StatusSkipped
+			result.Message = fmt.Sprintf("below the current used %s", used.String())
+			results = append(results, result)
+			continue
+		} else if !c.force && isAlreadyAtTarget(rq, key, want) {
+			klog.V(2).Infof("skip namespace/%s, the storageclass/%s limits of resourcequota/%s is already at the target value", rq.Namespace, class, rq.Name)
+			result.Status = StatusSkipped
+			result.Message = "already at the target value"
+			results = append(results, result)
+			continue
+		} else {
+			changes[key] = want
+		}
+		pending = append(pending, len(results))
+		results = append(results, result)
+	}
+
+	if len(pending) == 0 {
+		return results, utilerrors.NewAggregate(refused)
+	}
+
+	setStatus := func(status, message string) {
+		for _, i := range pending {
+			results[i].Status = status
+			results[i].Message = message
+		}
+	}
+
+	if c.checkOwnership {
+		keys := make([]string, 0, len(changes))
+		for key := range changes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		c.warnForeignOwners(rq, keys)
+	}
+
+	status, message, err := c.applyChanges(rq, changes)
+	if err != nil {
+		setStatus(status, message)
+		return results, err
 	}
+	switch {
+	case status != StatusPatched:
+		setStatus(status, message)
+	case c.annotationsOnly:
+		klog.V(2).Infof("successful annotated resourcequota/%s from namespace/%s", rq.Name, rq.Namespace)
+		setStatus(StatusPatched, "annotations only")
+	default:
+		klog.V(2).Infof("successful %s the storageclass/%s limits from namespace/%s", c.action, strings.Join(c.storageclasses, ","), rq.Namespace)
+		setStatus(StatusPatched, "")
+	}
+	return results, utilerrors.NewAggregate(refused)
+}
+
+// namespaceHasPVCs reports whether any persistentvolumeclaim in namespace uses one of the target storageclasses.
+func (c *Config) namespaceHasPVCs(namespace string) (bool, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	pvcs, err := c.client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	c.checkRequestTimeout(ctx, "list persistentvolumeclaims in namespace/"+namespace)
+	if err != nil {
+		return false, err
+	}
+
+	for _, pvc := range pvcs.Items {
+		for _, class := range c.storageclasses {
+			if pvcStorageClass(pvc) == class {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// namespacePVCClasses counts the persistentvolumeclaims of namespace by storageclass.
+func (c *Config) namespacePVCClasses(namespace string) (map[string]int, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	pvcs, err := c.client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	c.checkRequestTimeout(ctx, "list persistentvolumeclaims in namespace/"+namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	classes := map[string]int{}
+	for _, pvc := range pvcs.Items {
+		classes[pvcStorageClass(pvc)]++
+	}
+
+	return classes, nil
+}
+
+// pvcStorageClass returns the storageclass of pvc, honoring the deprecated beta annotation.
+func pvcStorageClass(pvc corev1.PersistentVolumeClaim) string {
+	if class, ok := pvc.Annotations[corev1.BetaStorageClassAnnotation]; ok {
+		return class
+	}
+	if pvc.Spec.StorageClassName != nil {
+		return *pvc.Spec.StorageClassName
+	}
+
+	return ""
+}
+
+// sortResourceQuotas orders rqs in place so that runs are reproducible.
+// Ties are broken by namespace and name.
+func sortResourceQuotas(rqs []corev1.ResourceQuota, by string) {
+	byName := func(a, b corev1.ResourceQuota) bool {
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	}
+
+	switch by {
+	case "name":
+		sort.SliceStable(rqs, func(i, j int) bool { return byName(rqs[i], rqs[j]) })
+	case "created":
+		sort.SliceStable(rqs, func(i, j int) bool {
+			ti, tj := rqs[i].CreationTimestamp, rqs[j].CreationTimestamp
+			if !ti.Equal(&tj) {
+				return ti.Before(&tj)
+			}
+			return byName(rqs[i], rqs[j])
+		})
+	}
+}
+
+// belowUsed reports whether setting key to want would put the hard limit below
+// what is already used in rq, and returns the used value.
+func belowUsed(rq corev1.ResourceQuota, key string, want *resource.Quantity) (resource.Quantity, bool) {
+	if want == nil {
+		return resource.Quantity{}, false
+	}
+	used, ok := rq.Status.Used[corev1.ResourceName(key)]
+	if !ok || want.Cmp(used) >= 0 {
+		return resource.Quantity{}, false
+	}
+	return used, true
+}
+
+// warnBelowUsed warns when setting key to want would put the hard limit below
+// what is already used, which makes the quota block every new claim at once.
+func warnBelowUsed(rq corev1.ResourceQuota, key string, want *resource.Quantity) {
+	used, ok := belowUsed(rq, key, want)
+	if !ok {
+		return
+	}
+
+	klog.Warningf("\033[31mresourcequota/%s in namespace/%s: new hard %s of %s is below the current used %s, new claims will be rejected immediately\033[0m",
+		rq.Name, rq.Namespace, key, want.String(), used.String())
+}
+
+// getExistingStorageQuota returns the value of key in the hard limits of rq, or nil if it is not set.
+func getExistingStorageQuota(rq corev1.ResourceQuota, key string) *resource.Quantity {
+	q, ok := hardValue(rq, key)
+	if !ok {
+		return nil
+	}
+	return &q
+}
+
+// hardLimits returns the hard limits of rq, never nil even when spec.hard is unset.
+func hardLimits(rq corev1.ResourceQuota) corev1.ResourceList {
+	if rq.Spec.Hard == nil {
+		return corev1.ResourceList{}
+	}
+	return rq.Spec.Hard
+}
+
+// hardValue returns the hard limit of key in rq and whether it is set.
+func hardValue(rq corev1.ResourceQuota, key string) (resource.Quantity, bool) {
+	q, ok := hardLimits(rq)[corev1.ResourceName(key)]
+	return q, ok
+}
+
+// wouldDecrease reports whether setting key in rq to want lowers the limit.
+// An absent key is unlimited, so setting any value decreases it and removing
+// the key never does.
+func wouldDecrease(rq corev1.ResourceQuota, key string, want *resource.Quantity) bool {
+	if want == nil {
+		return false
+	}
+	existing, ok := hardValue(rq, key)
+	if !ok {
+		return true
+	}
+
+	return want.Cmp(existing) < 0
+}
+
+// isAlreadyAtTarget reports whether key in the hard limits of rq already equals want.
+// A nil want means the key is expected to be absent.
+func isAlreadyAtTarget(rq corev1.ResourceQuota, key string, want *resource.Quantity) bool {
+	existing, ok := hardValue(rq, key)
+	if want == nil {
+		return !ok
+	}
+
+	return ok && quantitiesEqual(existing, *want)
+}
 
-	return utilerrors.NewAggregate(errorList)
+// quantitiesEqual compares quantities by value rather than by their string form,
+// so that equivalent values in different formats such as 5Gi and 5368709120 are equal.
+func quantitiesEqual(a, b resource.Quantity) bool {
+	return a.Cmp(b) == 0
 }
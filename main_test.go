@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func quantityPtr(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func TestIsAlreadyAtTarget(t *testing.T) {
+	key := "rbd.storageclass.storage.k8s.io/requests.storage"
+	rq := corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{
+		corev1.ResourceName(key): resource.MustParse("50Gi"),
+	}}}
+
+	tests := []struct {
+		name string
+		rq   corev1.ResourceQuota
+		want *resource.Quantity
+		at   bool
+	}{
+		{name: "equal", rq: rq, want: quantityPtr("50Gi"), at: true},
+		{name: "different", rq: rq, want: quantityPtr("100Gi"), at: false},
+		{name: "absent", rq: corev1.ResourceQuota{}, want: quantityPtr("50Gi"), at: false},
+		{name: "removed and absent", rq: corev1.ResourceQuota{}, want: nil, at: true},
+		{name: "removed but present", rq: rq, want: nil, at: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAlreadyAtTarget(tt.rq, key, tt.want); got != tt.at {
+				t.Errorf("isAlreadyAtTarget() = %v, want %v", got, tt.at)
+			}
+		})
+	}
+}
+
+func TestQuantitiesEqual(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{a: "5Gi", b: "5368709120", equal: true},
+		{a: "5Gi", b: "5120Mi", equal: true},
+		{a: "1G", b: "1000M", equal: true},
+		{a: "1Gi", b: "1G", equal: false},
+		{a: "0", b: "0Gi", equal: true},
+		{a: "50Gi", b: "51Gi", equal: false},
+	}
+	for _, tt := range tests {
+		if got := quantitiesEqual(resource.MustParse(tt.a), resource.MustParse(tt.b)); got != tt.equal {
+			t.Errorf("quantitiesEqual(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.equal)
+		}
+	}
+}
+
+func TestTargetForMissingSourceKey(t *testing.T) {
+	c := &Config{action: "add", size: "0", sourceKey: "requests.storage"}
+	rq := corev1.ResourceQuota{}
+	rq.Name, rq.Namespace = "quota", "team-a"
+
+	if want, err := c.targetFor(rq, "rbd"); err == nil {
+		t.Fatalf("targetFor() = %v, want an error for a resourcequota without %s", want, c.sourceKey)
+	}
+
+	rq.Spec.Hard = corev1.ResourceList{"requests.storage": resource.MustParse("100Gi")}
+	want, err := c.targetFor(rq, "rbd")
+	if err != nil {
+		t.Fatalf("targetFor() error = %v", err)
+	}
+	if !quantitiesEqual(*want, resource.MustParse("100Gi")) {
+		t.Errorf("targetFor() = %s, want 100Gi", want.String())
+	}
+}
+
+func TestOutOfScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		c         Config
+		namespace string
+		out       bool
+	}{
+		{name: "all namespaces", c: Config{}, namespace: "team-b", out: false},
+		{name: "other namespace", c: Config{namespace: "team-a"}, namespace: "team-b", out: true},
+		{name: "same namespace", c: Config{namespace: "team-a"}, namespace: "team-a", out: false},
+		{name: "excluded", c: Config{excludeNamespaces: []string{"team-*"}}, namespace: "team-b", out: true},
+		{name: "without prefix", c: Config{namespacePrefix: "tenant-"}, namespace: "team-b", out: true},
+		{name: "openshift", c: Config{platform: "openshift"}, namespace: "openshift-monitoring", out: true},
+		{name: "not in namespaces", c: Config{namespaces: []string{"team-a"}}, namespace: "team-b", out: true},
+		{name: "in namespaces", c: Config{namespaces: []string{"team-a", "team-b"}}, namespace: "team-b", out: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.outOfScope(tt.namespace); got != tt.out {
+				t.Errorf("outOfScope(%s) = %v, want %v", tt.namespace, got, tt.out)
+			}
+		})
+	}
+}
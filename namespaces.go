@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// platformExcludedNamespaces lists the namespaces that are always left alone on a given platform.
+var platformExcludedNamespaces = map[string][]string{
+	"kubernetes": nil,
+	"openshift":  {"openshift-*", "kube-*", "default"},
+}
+
+// quotaListOptions returns the options of every list of ResourceQuotas, which
+// only selects the ones matching --quota-selector.
+func (c *Config) quotaListOptions() metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: c.quotaSelector}
+}
+
+// listResourceQuotas lists the ResourceQuotas in scope, drops the ones in excluded
+// namespaces and sorts the rest. With --target only that ResourceQuota is fetched.
+func (c *Config) listResourceQuotas() ([]corev1.ResourceQuota, error) {
+	if c.targetName != "" {
+		ctx, cancel := c.requestContext()
+		defer cancel()
+		rq, err := c.getQuota(ctx, c.targetNamespace, c.targetName)
+		c.checkRequestTimeout(ctx, fmt.Sprintf("get resourcequota/%s in namespace/%s", c.targetName, c.targetNamespace))
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("resourcequota/%s not found in namespace/%s", c.targetName, c.targetNamespace)
+			}
+			return nil, err
+		}
+		return []corev1.ResourceQuota{*rq}, nil
+	}
+
+	var all []corev1.ResourceQuota
+	if len(c.namespaces) != 0 {
+		for _, ns := range c.namespaces {
+			ctx, cancel := c.requestContext()
+			rqs, err := c.listQuotas(ctx, ns, c.quotaListOptions())
+			c.checkRequestTimeout(ctx, "list resourcequotas in namespace/"+ns)
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("list resourcequotas in namespace/%s: %v", ns, err)
+			}
+			all = append(all, rqs.Items...)
+		}
+		if len(all) == 0 {
+			return nil, fmt.Errorf("no ResourceQuota found in namespaces %s", strings.Join(c.namespaces, ","))
+		}
+	} else {
+		ctx, cancel := c.requestContext()
+		defer cancel()
+		rqs, err := c.listQuotas(ctx, c.namespace, c.quotaListOptions())
+		c.checkRequestTimeout(ctx, "list resourcequotas")
+		if err != nil {
+			return nil, c.listHint(err)
+		}
+		if len(rqs.Items) == 0 {
+			return nil, fmt.Errorf("no ResourceQuota found in namespace/%s", c.namespace)
+		}
+		all = rqs.Items
+	}
+
+	items := make([]corev1.ResourceQuota, 0, len(all))
+	for _, rq := range all {
+		if c.isExcluded(rq.Namespace) {
+			klog.V(4).Infof("skip namespace/%s, it is excluded", rq.Namespace)
+			continue
+		}
+		items = append(items, rq)
+	}
+	sortResourceQuotas(items, c.sortBy)
+
+	return items, nil
+}
+
+// forEachResourceQuotaPage calls fn with the filtered and sorted ResourceQuotas in
+// scope. With --list-limit the ResourceQuotas are listed page by page and fn is
+// called once per page, so sorting only applies within a page. If the continue
+// token expires midway the listing restarts and already seen ResourceQuotas are
+// not handed to fn again. With --since-resource-version only the ResourceQuotas
+// changed since then are handed to fn, unless that version is too old or the
+// replay does not reach the current resourceVersion in time. An error from fn
+// stops the iteration and is returned.
+func (c *Config) forEachResourceQuotaPage(fn func([]corev1.ResourceQuota) error) error {
+	if c.sinceResourceVersion != "" && c.targetName == "" {
+		changed, current, err := c.changedResourceQuotas()
+		if err == nil {
+			items := make([]corev1.ResourceQuota, 0, len(changed))
+			for _, rq := range changed {
+				if c.isExcluded(rq.Namespace) {
+					klog.V(4).Infof("skip namespace/%s, it is excluded", rq.Namespace)
+					continue
+				}
+				items = append(items, rq)
+			}
+			sortResourceQuotas(items, c.sortBy)
+			klog.Infof("%d resourcequotas changed since resourceVersion %s, pass --since-resource-version=%s to the next run", len(items), c.sinceResourceVersion, current)
+			c.currentResourceVersion = current
+			return fn(items)
+		}
+		switch err {
+		case errResourceVersionGone:
+			klog.Warningf("resourceVersion %s is too old, fall back to a full list", c.sinceResourceVersion)
+		case errReplayIncomplete:
+			klog.Warningf("the changes since resourceVersion %s were not replayed within %v, fall back to a full list", c.sinceResourceVersion, watchReplayDeadline)
+		default:
+			return err
+		}
+	}
+
+	if c.listLimit == 0 || c.targetName != "" || len(c.namespaces) != 0 {
+		items, err := c.listResourceQuotas()
+		if err != nil {
+			return err
+		}
+		return fn(items)
+	}
+
+	seen := map[string]bool{}
+	opts := c.quotaListOptions()
+	opts.Limit = c.listLimit
+	for {
+		ctx, cancel := c.requestContext()
+		rqs, err := c.listQuotas(ctx, c.namespace, opts)
+		c.checkRequestTimeout(ctx, "list resourcequotas")
+		cancel()
+		if apierrors.IsResourceExpired(err) {
+			klog.Warningf("the continue token of the resourcequota list expired, restart listing: %v", err)
+			opts.Continue = ""
+			continue
+		}
+		if err != nil {
+			return c.listHint(err)
+		}
+		if len(seen) == 0 && len(rqs.Items) == 0 && rqs.Continue == "" {
+			return fmt.Errorf("no ResourceQuota found in namespace/%s", c.namespace)
+		}
+
+		page := make([]corev1.ResourceQuota, 0, len(rqs.Items))
+		for _, rq := range rqs.Items {
+			id := rq.Namespace + "/" + rq.Name
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if c.isExcluded(rq.Namespace) {
+				klog.V(4).Infof("skip namespace/%s, it is excluded", rq.Namespace)
+				continue
+			}
+			page = append(page, rq)
+		}
+		sortResourceQuotas(page, c.sortBy)
+		klog.V(4).Infof("processing a page of %d resourcequotas", len(page))
+
+		if err := fn(page); err != nil {
+			return err
+		}
+		if rqs.Continue == "" {
+			return nil
+		}
+		opts.Continue = rqs.Continue
+	}
+}
+
+// NamespacesWithoutQuota returns the namespaces in scope that are not excluded and
+// have no ResourceQuota at all, so nothing restricts their storage usage. With
+// --namespaces only those are candidates, and their ResourceQuotas are listed one
+// namespace at a time.
+func (c *Config) NamespacesWithoutQuota() ([]string, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	restricted := map[string]bool{}
+	var namespaces []string
+	switch {
+	case len(c.namespaces) != 0:
+		namespaces = c.namespaces
+		for _, ns := range namespaces {
+			rqs, err := c.listQuotas(ctx, ns, c.quotaListOptions())
+			c.checkRequestTimeout(ctx, "list resourcequotas in namespace/"+ns)
+			if err != nil {
+				return nil, err
+			}
+			restricted[ns] = len(rqs.Items) != 0
+		}
+		return c.unrestricted(namespaces, restricted), nil
+	case c.namespace != metav1.NamespaceAll:
+		namespaces = []string{c.namespace}
+	default:
+		list, err := c.client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		c.checkRequestTimeout(ctx, "list namespaces")
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range list.Items {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+
+	rqs, err := c.listQuotas(ctx, c.namespace, c.quotaListOptions())
+	c.checkRequestTimeout(ctx, "list resourcequotas")
+	if err != nil {
+		return nil, err
+	}
+	for _, rq := range rqs.Items {
+		restricted[rq.Namespace] = true
+	}
+
+	return c.unrestricted(namespaces, restricted), nil
+}
+
+// unrestricted returns the sorted namespaces that are not excluded and not restricted.
+func (c *Config) unrestricted(namespaces []string, restricted map[string]bool) []string {
+	var missing []string
+	for _, ns := range namespaces {
+		if c.isExcluded(ns) {
+			klog.V(4).Infof("skip namespace/%s, it is excluded", ns)
+			continue
+		}
+		if !restricted[ns] {
+			missing = append(missing, ns)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+// listHint points to --namespaces when the cluster-wide list of ResourceQuotas is forbidden.
+func (c *Config) listHint(err error) error {
+	if apierrors.IsForbidden(err) && c.namespace == metav1.NamespaceAll {
+		return fmt.Errorf("%v, use --namespaces or --namespaces-file to list the namespaces one by one", err)
+	}
+	return err
+}
+
+// readNamespacesFile reads one namespace per line from path, skipping blank lines and # comments.
+func readNamespacesFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error happened when reading namespaces file %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var namespaces []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		namespaces = append(namespaces, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error happened when reading namespaces file %s: %v", path, err)
+	}
+
+	return namespaces, nil
+}
+
+type namespaceGroup struct {
+	value string
+	items []corev1.ResourceQuota
+}
+
+// groupByNamespaceLabel splits rqs by the --group-by-label value of their
+// namespace. Groups are ordered by label value, namespaces without the label
+// form the last group, and the order of rqs is kept within a group.
+func (c *Config) groupByNamespaceLabel(rqs []corev1.ResourceQuota) ([]namespaceGroup, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	namespaces, err := c.client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	c.checkRequestTimeout(ctx, "list namespaces")
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	labeled := map[string]bool{}
+	for _, ns := range namespaces.Items {
+		if v, ok := ns.Labels[c.groupByLabel]; ok {
+			values[ns.Name] = v
+			labeled[ns.Name] = true
+		}
+	}
+
+	index := map[string]int{}
+	var groups []namespaceGroup
+	var unlabeled []corev1.ResourceQuota
+	for _, rq := range rqs {
+		if !labeled[rq.Namespace] {
+			unlabeled = append(unlabeled, rq)
+			continue
+		}
+		v := values[rq.Namespace]
+		i, ok := index[v]
+		if !ok {
+			i = len(groups)
+			index[v] = i
+			groups = append(groups, namespaceGroup{value: v})
+		}
+		groups[i].items = append(groups[i].items, rq)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].value < groups[j].value })
+	if len(unlabeled) != 0 {
+		groups = append(groups, namespaceGroup{value: "<unlabeled>", items: unlabeled})
+	}
+
+	return groups, nil
+}
+
+// outOfScope reports whether namespace is excluded or not selected by --namespace,
+// --target or --namespaces, for the namespaces read from a file rather than listed.
+func (c *Config) outOfScope(namespace string) bool {
+	if c.isExcluded(namespace) || (c.namespace != metav1.NamespaceAll && namespace != c.namespace) {
+		return true
+	}
+	if c.targetName != "" && namespace != c.targetNamespace {
+		return true
+	}
+	if len(c.namespaces) == 0 {
+		return false
+	}
+	for _, ns := range c.namespaces {
+		if ns == namespace {
+			return false
+		}
+	}
+	return true
+}
+
+// isExcluded reports whether namespace matches one of the --exclude-namespace patterns
+// or the namespaces excluded by --platform, or lacks the --namespace-prefix. The
+// --template-namespace is never a target.
+func (c *Config) isExcluded(namespace string) bool {
+	if c.templateNamespace != "" && namespace == c.templateNamespace {
+		return true
+	}
+	if c.namespacePrefix != "" && !strings.HasPrefix(namespace, c.namespacePrefix) {
+		return true
+	}
+	patterns := append(platformExcludedNamespaces[c.platform], c.excludeNamespaces...)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+
+	return false
+}
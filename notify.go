@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// notify POSTs the JSON summary of the run to --notify-url, retrying up to
+// --notify-retries times. A failed notification is returned for logging and
+// never changes the outcome of the run.
+func (c *Config) notify(summary Summary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: c.notifyTimeout}
+	for attempt := 0; ; attempt++ {
+		err = postJSON(client, c.notifyURL, data)
+		if err == nil || attempt >= c.notifyRetries {
+			return err
+		}
+		klog.V(2).Infof("notify %s failed, retry %d/%d: %v", c.notifyURL, attempt+1, c.notifyRetries, err)
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+	}
+}
+
+func postJSON(client *http.Client, url string, data []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
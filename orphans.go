@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+// FindOrphanedKeys reports the storageclass quota keys of the ResourceQuotas in
+// scope whose storageclass does not exist anymore, and removes them with --prune.
+func (c *Config) FindOrphanedKeys() ([]Result, error) {
+	ctx, cancel := c.requestContext()
+	scs, err := c.client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	c.checkRequestTimeout(ctx, "list storageclasses")
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(scs.Items))
+	for _, sc := range scs.Items {
+		existing[sc.Name] = true
+	}
+
+	var results []Result
+	var errorList []error
+	err = c.forEachResourceQuotaPage(func(rqs []corev1.ResourceQuota) error {
+		for _, rq := range rqs {
+			rqResults, err := c.pruneResourceQuota(rq, existing)
+			results = append(results, rqResults...)
+			if err != nil {
+				errorList = append(errorList, err)
+				if c.exitOnFirstError {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil && len(errorList) == 0 {
+		errorList = append(errorList, err)
+	}
+
+	return results, utilerrors.NewAggregate(errorList)
+}
+
+// pruneResourceQuota returns one Result per orphaned storageclass key of rq and
+// removes the keys with a single patch when --prune is set.
+func (c *Config) pruneResourceQuota(rq corev1.ResourceQuota, existing map[string]bool) ([]Result, error) {
+	var keys []string
+	for name := range hardLimits(rq) {
+		class, _, ok := parseQuotaKey(c.keyFormat, string(name))
+		if ok && !existing[class] {
+			keys = append(keys, string(name))
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sort.Strings(keys)
+
+	results := make([]Result, 0, len(keys))
+	changes := make(map[string]*resource.Quantity, len(keys))
+	for _, key := range keys {
+		class, _, _ := parseQuotaKey(c.keyFormat, key)
+		old, _ := hardValue(rq, key)
+		klog.Warningf("namespace/%s resourcequota/%s key %q refers to storageclass/%s which does not exist", rq.Namespace, rq.Name, key, class)
+		results = append(results, Result{Namespace: rq.Namespace, Quota: rq.Name, StorageClass: class, Action: c.action, Old: old.String(), Status: StatusReported, Message: "storageclass not found"})
+		changes[key] = nil
+	}
+	if !c.prune {
+		return results, nil
+	}
+
+	status, message, err := c.applyChanges(rq, changes)
+	if status == StatusPatched {
+		klog.V(2).Infof("successful pruned %d orphaned keys from resourcequota/%s in namespace/%s", len(keys), rq.Name, rq.Namespace)
+		message = "pruned"
+	}
+	for i := range results {
+		results[i].Status = status
+		results[i].Message = message
+	}
+
+	return results, err
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Output formats accepted by --output.
+const (
+	outputText  = "text"
+	outputTable = "table"
+	outputWide  = "wide"
+	outputJSON  = "json"
+)
+
+// defaultOutput picks table for an interactive terminal and text otherwise.
+func defaultOutput() string {
+	if fi, err := os.Stdout.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		return outputTable
+	}
+	return outputText
+}
+
+// printResults writes results to stdout in the --output format. The text format
+// relies on the klog progress lines on stderr and prints nothing.
+func (c *Config) printResults(results []Result) error {
+	results = sortResults(results, c.outputSort)
+	if c.redacted {
+		var err error
+		if results, err = c.redact(results); err != nil {
+			return err
+		}
+	}
+	if c.relativeChange {
+		results = append([]Result(nil), results...)
+		for i := range results {
+			results[i].Change = relativeChange(results[i].Old, results[i].New)
+		}
+	}
+
+	switch c.output {
+	case outputTable:
+		return printTable(os.Stdout, results, c.relativeChange, nil)
+	case outputWide:
+		return printTable(os.Stdout, results, c.relativeChange, c.storageclassDetails())
+	case outputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	return nil
+}
+
+// printTable writes results as a table. With classes it also shows the
+// provisioner and volumeBindingMode of the storage class of each result.
+func printTable(out io.Writer, results []Result, withChange bool, classes map[string]StorageclassDetails) error {
+	w := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	header := []string{"NAMESPACE", "QUOTA", "STORAGECLASS"}
+	if classes != nil {
+		header = append(header, "PROVISIONER", "BINDINGMODE")
+	}
+	header = append(header, "OLD", "NEW")
+	if withChange {
+		header = append(header, "CHANGE")
+	}
+	fmt.Fprintln(w, strings.Join(append(header, "ACTION", "STATUS"), "\t"))
+	for _, r := range results {
+		row := []string{r.Namespace, orNone(r.Quota), orNone(r.StorageClass)}
+		if classes != nil {
+			d := classes[r.StorageClass]
+			row = append(row, orNone(d.Provisioner), orNone(d.VolumeBindingMode))
+		}
+		row = append(row, orNone(r.Old), orNone(r.New))
+		if withChange {
+			row = append(row, orNone(r.Change))
+		}
+		fmt.Fprintln(w, strings.Join(append(row, r.Action, r.Status), "\t"))
+	}
+
+	return w.Flush()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+// redact replaces the namespaces in results with ns-0001, ns-0002, ... in the
+// order of their names and writes the mapping to --redaction-map, so the output
+// can be shared without naming tenants. The namespace/<name> references of the
+// messages and the extra namespaces get an alias too, for redactText.
+// The logs on stderr are not redacted.
+func (c *Config) redact(results []Result, extra ...string) ([]Result, error) {
+	if c.redactions == nil {
+		c.redactions = map[string]string{}
+	}
+	var names []string
+	for _, r := range results {
+		extra = append(extra, r.Namespace)
+		extra = append(extra, namespacesIn(r.Message)...)
+	}
+	for _, namespace := range extra {
+		if _, ok := c.redactions[namespace]; !ok {
+			c.redactions[namespace] = ""
+			names = append(names, namespace)
+		}
+	}
+	sort.Strings(names)
+	next := len(c.redactions) - len(names)
+	for _, name := range names {
+		next++
+		c.redactions[name] = fmt.Sprintf("ns-%04d", next)
+	}
+
+	redacted := make([]Result, len(results))
+	for i, r := range results {
+		r.Message = c.redactText(r.Message)
+		r.Namespace = c.redactions[r.Namespace]
+		redacted[i] = r
+	}
+
+	if c.redactionMap != "" {
+		mapping := make(map[string]string, len(c.redactions))
+		for name, alias := range c.redactions {
+			mapping[alias] = name
+		}
+		data, err := json.MarshalIndent(mapping, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(c.redactionMap, append(data, '\n'), 0o600); err != nil {
+			return nil, fmt.Errorf("error happened when writing redaction map %s: %v", c.redactionMap, err)
+		}
+	}
+
+	return redacted, nil
+}
+
+// statusRank orders the statuses for --output-sort=status, the ones that need
+// attention first.
+var statusRank = map[string]int{
+	StatusFailed:     0,
+	StatusDrifted:    1,
+	StatusRolledBack: 2,
+	StatusPlanned:    3,
+	StatusPatched:    4,
+	StatusReported:   5,
+	StatusMatched:    6,
+	StatusSkipped:    7,
+}
+
+// sortResults returns a copy of results ordered by status, namespace or value
+// (the new value, or the old one when there is none, unset values last). Ties
+// are broken by namespace, quota and storageclass.
+func sortResults(results []Result, by string) []Result {
+	sorted := append([]Result(nil), results...)
+	value := func(r Result) *resource.Quantity {
+		v := r.New
+		if v == "" {
+			v = r.Old
+		}
+		if q, err := resource.ParseQuantity(v); err == nil {
+			return &q
+		}
+		return nil
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		switch by {
+		case "status":
+			if statusRank[a.Status] != statusRank[b.Status] {
+				return statusRank[a.Status] < statusRank[b.Status]
+			}
+		case "value":
+			va, vb := value(a), value(b)
+			if (va == nil) != (vb == nil) {
+				return vb == nil
+			}
+			if va != nil && va.Cmp(*vb) != 0 {
+				return va.Cmp(*vb) < 0
+			}
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Quota != b.Quota {
+			return a.Quota < b.Quota
+		}
+		return a.StorageClass < b.StorageClass
+	})
+
+	return sorted
+}
+
+// namespaceReference matches the namespace/<name> references of the messages.
+var namespaceReference = regexp.MustCompile(`namespace/([a-z0-9]([-a-z0-9]*[a-z0-9])?)`)
+
+// namespacesIn returns the namespaces referenced as namespace/<name> in s.
+func namespacesIn(s string) []string {
+	var namespaces []string
+	for _, m := range namespaceReference.FindAllStringSubmatch(s, -1) {
+		namespaces = append(namespaces, m[1])
+	}
+	return namespaces
+}
+
+// redactText replaces the namespace/<name> references in s with their alias
+// from redact, references without an alias are replaced with namespace/<redacted>.
+func (c *Config) redactText(s string) string {
+	return namespaceReference.ReplaceAllStringFunc(s, func(ref string) string {
+		if alias, ok := c.redactions[strings.TrimPrefix(ref, "namespace/")]; ok {
+			return "namespace/" + alias
+		}
+		return "namespace/<redacted>"
+	})
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestRedactMessage(t *testing.T) {
+	c := &Config{}
+	results := []Result{
+		{Namespace: "a", Message: "already at the target value"},
+		{Namespace: "b", Message: "conflict with namespace/a and namespace/c"},
+	}
+
+	redacted, err := c.redact(results)
+	if err != nil {
+		t.Fatalf("redact() error = %v", err)
+	}
+	want := []Result{
+		{Namespace: "ns-0001", Message: "already at the target value"},
+		{Namespace: "ns-0002", Message: "conflict with namespace/ns-0001 and namespace/ns-0003"},
+	}
+	for i := range want {
+		if redacted[i].Namespace != want[i].Namespace || redacted[i].Message != want[i].Message {
+			t.Errorf("redact()[%d] = %s %q, want %s %q", i, redacted[i].Namespace, redacted[i].Message, want[i].Namespace, want[i].Message)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// hardKeyOwners returns the field managers other than ours that own
+// spec.hard[key] of rq according to its managedFields.
+func hardKeyOwners(rq corev1.ResourceQuota, key string) []string {
+	var owners []string
+	for _, entry := range rq.ManagedFields {
+		if entry.Manager == fieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+
+		var fields map[string]map[string]map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			klog.V(4).Infof("failed to decode managed fields of %s on resourcequota/%s: %v", entry.Manager, rq.Name, err)
+			continue
+		}
+		if _, ok := fields["f:spec"]["f:hard"]["f:"+key]; ok {
+			owners = append(owners, entry.Manager)
+		}
+	}
+
+	return owners
+}
+
+// warnForeignOwners warns when the keys about to be patched are owned by
+// another field manager, which will likely revert our change.
+func (c *Config) warnForeignOwners(rq corev1.ResourceQuota, keys []string) {
+	for _, key := range keys {
+		for _, owner := range hardKeyOwners(rq, key) {
+			klog.Warningf("%s of resourcequota/%s in namespace/%s is owned by field manager %q, it may revert this change", key, rq.Name, rq.Namespace, owner)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+)
+
+// Annotations written to ResourceQuotas touched by the tool.
+const (
+	annotationManagedBy     = "storageclass-restrict.tiggoins.io/managed-by"
+	annotationLastAction    = "storageclass-restrict.tiggoins.io/last-action"
+	annotationLastAppliedAt = "storageclass-restrict.tiggoins.io/last-applied-at"
+	annotationReason        = "storageclass-restrict.tiggoins.io/reason"
+	annotationTicket        = "storageclass-restrict.tiggoins.io/ticket"
+
+	fieldManager = "storageclass-restriction"
+)
+
+// managedAnnotations returns the annotations that mark a ResourceQuota as managed by the tool.
+func (c *Config) managedAnnotations() map[string]string {
+	action := fmt.Sprintf("%s storageclass/%s", c.action, strings.Join(c.storageclasses, ","))
+	if c.action == "orphans" {
+		action = "prune orphaned storageclass keys"
+	} else if c.action == "add" && c.sourceKey != "" {
+		action = fmt.Sprintf("%s from %s", action, c.sourceKey)
+	} else if c.action == "sync" {
+		action = fmt.Sprintf("%s from namespace/%s", action, c.templateNamespace)
+	} else if c.action == "import" {
+		action = fmt.Sprintf("%s from %s", action, filepath.Base(c.inputFile))
+	} else if c.action == "add" && c.sizePercent > 0 {
+		action = fmt.Sprintf("%s=%v%% of %s", action, c.sizePercent, requestsStorageSuffix)
+	} else if c.action == "add" && c.delta != nil {
+		action = fmt.Sprintf("%s by %s", action, c.deltaValue)
+	} else if c.action == "add" {
+		action = fmt.Sprintf("%s=%s", action, c.size)
+	}
+
+	annotations := map[string]string{
+		annotationManagedBy:     fieldManager,
+		annotationLastAction:    action,
+		annotationLastAppliedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if c.reason != "" {
+		annotations[annotationReason] = c.reason
+	}
+	if c.ticket != "" {
+		annotations[annotationTicket] = c.ticket
+	}
+
+	return annotations
+}
+
+var resourceQuotaGVR = schema.GroupVersionResource{Version: "v1", Resource: "resourcequotas"}
+
+// patch sends the patch through the dynamic client with --dynamic and through the typed client otherwise.
+func (c *Config) patch(ctx context.Context, namespace, name string, pt types.PatchType, data []byte) error {
+	opts := metav1.PatchOptions{FieldManager: fieldManager}
+	if c.dynamicClient != nil {
+		_, err := c.dynamicClient.Resource(c.quotaResource).Namespace(namespace).Patch(ctx, name, pt, data, opts)
+		return err
+	}
+
+	_, err := c.client.CoreV1().ResourceQuotas(namespace).Patch(ctx, name, pt, data, opts)
+	return err
+}
+
+// applyChanges sends the patch that brings the hard limits of rq to changes and
+// returns the status and message of the results it covers. Every action patches
+// through it, so a patch that would leave the hard limits as they are is never
+// sent, unless --force is given or only the annotations are written.
+func (c *Config) applyChanges(rq corev1.ResourceQuota, changes map[string]*resource.Quantity) (string, string, error) {
+	if !c.force && !c.annotationsOnly && hardUnchanged(rq, changes) {
+		klog.V(2).Infof("skip namespace/%s, the patch would not change resourcequota/%s", rq.Namespace, rq.Name)
+		return StatusSkipped, "no change", nil
+	}
+
+	patchData, err := c.buildPatch(rq, changes)
+	if err != nil {
+		return StatusFailed, err.Error(), err
+	}
+
+	if c.printKubectl {
+		fmt.Println(kubectlPatchCommand(c.kubectlResource(), rq, c.patchType, patchData))
+	}
+
+	for key, want := range changes {
+		warnBelowUsed(rq, key, want)
+	}
+	if c.dryRun {
+		klog.Infof("[dry-run] would patch resourcequota/%s in namespace/%s: %s", rq.Name, rq.Namespace, patchData)
+		return StatusPlanned, "dry-run", nil
+	}
+
+	if c.pauseBetween > 0 && c.lastPatchedNamespace != "" && c.lastPatchedNamespace != rq.Namespace {
+		klog.V(2).Infof("pause %v before patching namespace/%s", c.pauseBetween, rq.Namespace)
+		select {
+		case <-time.After(c.pauseBetween):
+		case <-c.context.Done():
+			return StatusFailed, c.context.Err().Error(), c.context.Err()
+		}
+	}
+
+	patchType := patchTypes[c.patchType]
+	err = retry.OnError(c.backoff(), isTransient, func() error {
+		ctx, cancel := c.requestContext()
+		defer cancel()
+		start := time.Now()
+		err := c.patch(ctx, rq.Namespace, rq.Name, patchType, patchData)
+		c.timings.observe(time.Since(start))
+		c.checkRequestTimeout(ctx, fmt.Sprintf("patch resourcequota/%s in namespace/%s", rq.Name, rq.Namespace))
+		if err != nil && isTransient(err) {
+			klog.Warningf("patch resourcequota/%s in namespace/%s failed, retrying: %v", rq.Name, rq.Namespace, err)
+		}
+		return err
+	})
+	if err != nil && apierrors.IsConflict(err) {
+		return StatusFailed, "conflict, the resourcequota changed since it was listed: " + err.Error(), err
+	}
+	if err != nil {
+		return StatusFailed, err.Error(), err
+	}
+	c.lastPatchedNamespace = rq.Namespace
+	if c.transactional && !c.annotationsOnly {
+		c.record(rq, changes)
+	}
+	if c.reason != "" || c.ticket != "" {
+		c.emitEvent(rq)
+	}
+
+	return StatusPatched, "", nil
+}
+
+// hardUnchanged reports whether applying changes would leave the hard limits of rq as they are.
+func hardUnchanged(rq corev1.ResourceQuota, changes map[string]*resource.Quantity) bool {
+	for key, want := range changes {
+		if !isAlreadyAtTarget(rq, key, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// patchTypes maps the --patch-type values to the patch types sent to the apiserver.
+var patchTypes = map[string]types.PatchType{
+	"strategic": types.StrategicMergePatchType,
+	"merge":     types.MergePatchType,
+	"json":      types.JSONPatchType,
+}
+
+// buildPatch renders the patch that sets every key of changes in rq to its value,
+// or removes it when the value is nil. With --patch-annotations-only the hard
+// limits are left out and only the managed annotations are written.
+func (c *Config) buildPatch(rq corev1.ResourceQuota, changes map[string]*resource.Quantity) ([]byte, error) {
+	if c.patchType == "json" {
+		return c.buildJSONPatch(rq, changes)
+	}
+
+	// Strategic merge and json merge patches share the same shape here, a null
+	// value deletes the key in both.
+	patch := map[string]interface{}{}
+	if !c.annotationsOnly {
+		hard := map[string]interface{}{}
+		for key, want := range changes {
+			if want == nil {
+				hard[key] = nil
+				continue
+			}
+			hard[key] = want.String()
+		}
+		patch["spec"] = map[string]interface{}{"hard": hard}
+	}
+	metadata := map[string]interface{}{}
+	if c.annotationsOnly || c.annotateManaged {
+		metadata["annotations"] = c.managedAnnotations()
+	}
+	if c.labelKey != "" {
+		metadata["labels"] = map[string]string{c.labelKey: c.labelValue}
+	}
+	if c.optimistic {
+		// A stale resourceVersion makes the apiserver reject the patch with a conflict.
+		metadata["resourceVersion"] = rq.ResourceVersion
+	}
+	if len(metadata) != 0 {
+		patch["metadata"] = metadata
+	}
+
+	return json.Marshal(patch)
+}
+
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// buildJSONPatch renders changes as RFC 6902 operations. Parent maps that do not
+// exist yet are created first, since "add" cannot create intermediate members,
+// and keys that are already absent are not removed again.
+func (c *Config) buildJSONPatch(rq corev1.ResourceQuota, changes map[string]*resource.Quantity) ([]byte, error) {
+	ops := []jsonPatchOperation{}
+	if !c.annotationsOnly {
+		keys := make([]string, 0, len(changes))
+		for key := range changes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		if rq.Spec.Hard == nil {
+			ops = append(ops, jsonPatchOperation{Op: "add", Path: "/spec/hard", Value: map[string]string{}})
+		}
+		for _, key := range keys {
+			path := "/spec/hard/" + escapeJSONPointer(key)
+			if want := changes[key]; want != nil {
+				ops = append(ops, jsonPatchOperation{Op: "add", Path: path, Value: want.String()})
+			} else if _, ok := hardValue(rq, key); ok {
+				ops = append(ops, jsonPatchOperation{Op: "remove", Path: path})
+			}
+		}
+	}
+	if c.annotationsOnly || c.annotateManaged {
+		annotations := c.managedAnnotations()
+		if rq.Annotations == nil {
+			ops = append(ops, jsonPatchOperation{Op: "add", Path: "/metadata/annotations", Value: annotations})
+		} else {
+			keys := make([]string, 0, len(annotations))
+			for key := range annotations {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				ops = append(ops, jsonPatchOperation{Op: "add", Path: "/metadata/annotations/" + escapeJSONPointer(key), Value: annotations[key]})
+			}
+		}
+	}
+
+	if c.labelKey != "" {
+		if rq.Labels == nil {
+			ops = append(ops, jsonPatchOperation{Op: "add", Path: "/metadata/labels", Value: map[string]string{c.labelKey: c.labelValue}})
+		} else {
+			ops = append(ops, jsonPatchOperation{Op: "add", Path: "/metadata/labels/" + escapeJSONPointer(c.labelKey), Value: c.labelValue})
+		}
+	}
+	if c.optimistic {
+		ops = append(ops, jsonPatchOperation{Op: "replace", Path: "/metadata/resourceVersion", Value: rq.ResourceVersion})
+	}
+
+	return json.Marshal(ops)
+}
+
+// escapeJSONPointer escapes a map key for use in a JSON pointer (RFC 6901).
+func escapeJSONPointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// kubectlPatchCommand renders the kubectl command that sends the same patch to rq
+// of the given resource, quoted for a POSIX shell.
+func kubectlPatchCommand(resourceArg string, rq corev1.ResourceQuota, patchType string, data []byte) string {
+	quoted := "'" + strings.ReplaceAll(string(data), "'", `'\''`) + "'"
+	return fmt.Sprintf("kubectl patch %s %s -n %s --type=%s -p %s", resourceArg, rq.Name, rq.Namespace, patchType, quoted)
+}
+
+// kubectlResource is the resource argument of kubectl for the resourcequotas the
+// tool patches, fully qualified for a --dynamic-resource other than the core one.
+func (c *Config) kubectlResource() string {
+	if c.quotaResource == resourceQuotaGVR || c.quotaResource.Empty() {
+		return "resourcequota"
+	}
+	return fmt.Sprintf("%s.%s.%s", c.quotaResource.Resource, c.quotaResource.Version, c.quotaResource.Group)
+}
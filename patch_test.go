@@ -0,0 +1,172 @@
+package main
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestNilHard(t *testing.T) {
+	rq := corev1.ResourceQuota{}
+	key := "rbd.storageclass.storage.k8s.io/requests.storage"
+
+	if hard := hardLimits(rq); hard == nil || len(hard) != 0 {
+		t.Errorf("hardLimits() = %v, want an empty list", hard)
+	}
+	if _, ok := hardValue(rq, key); ok {
+		t.Errorf("hardValue() reports %s as set on a nil spec.hard", key)
+	}
+
+	tests := []struct {
+		name      string
+		patchType string
+		changes   map[string]*resource.Quantity
+		want      string
+	}{
+		{
+			name:      "strategic set",
+			patchType: "strategic",
+			changes:   map[string]*resource.Quantity{key: quantityPtr("0")},
+			want:      `{"spec":{"hard":{"rbd.storageclass.storage.k8s.io/requests.storage":"0"}}}`,
+		},
+		{
+			name:      "merge set",
+			patchType: "merge",
+			changes:   map[string]*resource.Quantity{key: quantityPtr("50Gi")},
+			want:      `{"spec":{"hard":{"rbd.storageclass.storage.k8s.io/requests.storage":"50Gi"}}}`,
+		},
+		{
+			name:      "json set creates spec.hard",
+			patchType: "json",
+			changes:   map[string]*resource.Quantity{key: quantityPtr("50Gi")},
+			want:      `[{"op":"add","path":"/spec/hard","value":{}},{"op":"add","path":"/spec/hard/rbd.storageclass.storage.k8s.io~1requests.storage","value":"50Gi"}]`,
+		},
+		{
+			name:      "json remove of an absent key",
+			patchType: "json",
+			changes:   map[string]*resource.Quantity{key: nil},
+			want:      `[{"op":"add","path":"/spec/hard","value":{}}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{patchType: tt.patchType}
+			got, err := c.buildPatch(rq, tt.changes)
+			if err != nil {
+				t.Fatalf("buildPatch() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("buildPatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPatch(t *testing.T) {
+	rbd := "rbd.storageclass.storage.k8s.io/requests.storage"
+	cephfs := "cephfs.storageclass.storage.k8s.io/requests.storage"
+	odd := "fast~ssd.storageclass.storage.k8s.io/requests.storage"
+	rq := corev1.ResourceQuota{Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{
+		corev1.ResourceName(rbd):    resource.MustParse("10Gi"),
+		corev1.ResourceName(cephfs): resource.MustParse("20Gi"),
+		corev1.ResourceName(odd):    resource.MustParse("30Gi"),
+	}}}
+
+	tests := []struct {
+		name      string
+		patchType string
+		labelKey  string
+		changes   map[string]*resource.Quantity
+		want      string
+	}{
+		{
+			name:      "strategic single class",
+			patchType: "strategic",
+			changes:   map[string]*resource.Quantity{rbd: quantityPtr("50Gi")},
+			want:      `{"spec":{"hard":{"rbd.storageclass.storage.k8s.io/requests.storage":"50Gi"}}}`,
+		},
+		{
+			name:      "strategic multi class with a removal",
+			patchType: "strategic",
+			changes:   map[string]*resource.Quantity{rbd: quantityPtr("0"), cephfs: nil},
+			want:      `{"spec":{"hard":{"cephfs.storageclass.storage.k8s.io/requests.storage":null,"rbd.storageclass.storage.k8s.io/requests.storage":"0"}}}`,
+		},
+		{
+			name:      "merge with label",
+			patchType: "merge",
+			labelKey:  "storageclass-restrict.tiggoins.io/managed",
+			changes:   map[string]*resource.Quantity{rbd: quantityPtr("50Gi")},
+			want:      `{"metadata":{"labels":{"storageclass-restrict.tiggoins.io/managed":"true"}},"spec":{"hard":{"rbd.storageclass.storage.k8s.io/requests.storage":"50Gi"}}}`,
+		},
+		{
+			name:      "json single class",
+			patchType: "json",
+			changes:   map[string]*resource.Quantity{rbd: quantityPtr("50Gi")},
+			want:      `[{"op":"add","path":"/spec/hard/rbd.storageclass.storage.k8s.io~1requests.storage","value":"50Gi"}]`,
+		},
+		{
+			name:      "json special characters",
+			patchType: "json",
+			labelKey:  "storageclass-restrict.tiggoins.io/managed",
+			changes:   map[string]*resource.Quantity{odd: nil},
+			want:      `[{"op":"remove","path":"/spec/hard/fast~0ssd.storageclass.storage.k8s.io~1requests.storage"},{"op":"add","path":"/metadata/labels","value":{"storageclass-restrict.tiggoins.io/managed":"true"}}]`,
+		},
+		{
+			name:      "json multi class in key order",
+			patchType: "json",
+			changes:   map[string]*resource.Quantity{rbd: quantityPtr("1Ti"), cephfs: quantityPtr("0")},
+			want:      `[{"op":"add","path":"/spec/hard/cephfs.storageclass.storage.k8s.io~1requests.storage","value":"0"},{"op":"add","path":"/spec/hard/rbd.storageclass.storage.k8s.io~1requests.storage","value":"1Ti"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{patchType: tt.patchType, labelKey: tt.labelKey}
+			if tt.labelKey != "" {
+				c.labelValue = "true"
+			}
+			got, err := c.buildPatch(rq, tt.changes)
+			if err != nil {
+				t.Fatalf("buildPatch() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("buildPatch() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubectlPatchCommand(t *testing.T) {
+	rq := corev1.ResourceQuota{}
+	rq.Name, rq.Namespace = "storage", "team-a"
+	got := kubectlPatchCommand("resourcequota", rq, "merge", []byte(`{"metadata":{"annotations":{"reason":"it's full"}}}`))
+	want := `kubectl patch resourcequota storage -n team-a --type=merge -p '{"metadata":{"annotations":{"reason":"it'\''s full"}}}'`
+	if got != want {
+		t.Errorf("kubectlPatchCommand() = %s, want %s", got, want)
+	}
+}
+
+func TestKubectlResource(t *testing.T) {
+	tests := []struct {
+		gvr  schema.GroupVersionResource
+		want string
+	}{
+		{gvr: resourceQuotaGVR, want: "resourcequota"},
+		{gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1alpha1", Resource: "quotas"}, want: "quotas.v1alpha1.example.com"},
+	}
+	for _, tt := range tests {
+		c := &Config{quotaResource: tt.gvr}
+		if got := c.kubectlResource(); got != tt.want {
+			t.Errorf("kubectlResource() for %v = %s, want %s", tt.gvr, got, tt.want)
+		}
+	}
+}
+
+func TestEventMessage(t *testing.T) {
+	c := &Config{action: "remove", storageclasses: []string{"rbd"}, reason: "decommission", ticket: "CHG-42"}
+	want := "remove storageclass/rbd, reason: decommission, ticket: CHG-42"
+	if got := c.eventMessage(); got != want {
+		t.Errorf("eventMessage() = %q, want %q", got, want)
+	}
+}
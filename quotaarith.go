@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"gopkg.in/inf.v0"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// quotaArith collects the arithmetic on quota values. resource.Quantity silently
+// switches to an arbitrary precision decimal when a value leaves the int64 range,
+// which the apiserver then rejects, so every result is checked to still fit.
+type quotaArith struct{}
+
+// add returns a+b in the format of a, or of b when a is zero and carries none.
+func (quotaArith) add(a, b resource.Quantity) (resource.Quantity, error) {
+	sum := a.DeepCopy()
+	if sum.IsZero() && sum.Format == "" {
+		sum.Format = b.Format
+	}
+	sum.Add(b)
+	if !fitsInt64(sum.AsDec()) {
+		return resource.Quantity{}, fmt.Errorf("%s + %s overflows", a.String(), b.String())
+	}
+
+	return sum, nil
+}
+
+// scale returns q*factor rounded up to a whole unit, in the format of q.
+func (quotaArith) scale(q resource.Quantity, factor float64) (resource.Quantity, error) {
+	if math.IsNaN(factor) || math.IsInf(factor, 0) {
+		return resource.Quantity{}, fmt.Errorf("invalid factor %v", factor)
+	}
+	f, ok := new(inf.Dec).SetString(strconv.FormatFloat(factor, 'f', -1, 64))
+	if !ok {
+		return resource.Quantity{}, fmt.Errorf("invalid factor %v", factor)
+	}
+
+	product := new(inf.Dec).Mul(q.AsDec(), f)
+	product.Round(product, 0, inf.RoundCeil)
+	if !fitsInt64(product) {
+		return resource.Quantity{}, fmt.Errorf("%s * %v overflows", q.String(), factor)
+	}
+	v, _ := product.Unscaled()
+
+	return *resource.NewQuantity(v, q.Format), nil
+}
+
+// clamp returns q raised to min and lowered to max, a nil bound is ignored.
+func (quotaArith) clamp(q resource.Quantity, min, max *resource.Quantity) resource.Quantity {
+	if min != nil && q.Cmp(*min) < 0 {
+		return min.DeepCopy()
+	}
+	if max != nil && q.Cmp(*max) > 0 {
+		return max.DeepCopy()
+	}
+
+	return q
+}
+
+func fitsInt64(d *inf.Dec) bool {
+	return d.Cmp(inf.NewDec(math.MaxInt64, 0)) <= 0 && d.Cmp(inf.NewDec(math.MinInt64, 0)) >= 0
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestQuotaArithAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    resource.Quantity
+		want    string
+		wantErr bool
+	}{
+		{name: "same unit", a: resource.MustParse("50Gi"), b: resource.MustParse("10Gi"), want: "60Gi"},
+		{name: "Mi plus Gi", a: resource.MustParse("512Mi"), b: resource.MustParse("1Gi"), want: "1536Mi"},
+		{name: "negative delta", a: resource.MustParse("50Gi"), b: resource.MustParse("-10Gi"), want: "40Gi"},
+		{name: "zero takes the format of b", a: resource.Quantity{}, b: resource.MustParse("10Gi"), want: "10Gi"},
+		{name: "overflow", a: *resource.NewQuantity(math.MaxInt64, resource.DecimalSI), b: resource.MustParse("1"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := quotaArith{}.add(tt.a, tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("add() = %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotaArithScale(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       resource.Quantity
+		factor  float64
+		want    string
+		wantErr bool
+	}{
+		{name: "double", q: resource.MustParse("50Gi"), factor: 2, want: "100Gi"},
+		{name: "half", q: resource.MustParse("50Gi"), factor: 0.5, want: "25Gi"},
+		{name: "fraction rounds up", q: resource.MustParse("10"), factor: 0.33, want: "4"},
+		{name: "percentage", q: resource.MustParse("100Gi"), factor: 0.25, want: "25Gi"},
+		{name: "zero", q: resource.MustParse("50Gi"), factor: 0, want: "0"},
+		{name: "overflow", q: *resource.NewQuantity(math.MaxInt64, resource.DecimalSI), factor: 2, wantErr: true},
+		{name: "NaN", q: resource.MustParse("50Gi"), factor: math.NaN(), wantErr: true},
+		{name: "infinity", q: resource.MustParse("50Gi"), factor: math.Inf(1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := quotaArith{}.scale(tt.q, tt.factor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scale() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("scale() = %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotaArithClamp(t *testing.T) {
+	min, max := resource.MustParse("10Gi"), resource.MustParse("1Ti")
+	tests := []struct {
+		name     string
+		q        string
+		min, max *resource.Quantity
+		want     string
+	}{
+		{name: "within", q: "50Gi", min: &min, max: &max, want: "50Gi"},
+		{name: "below min", q: "5Gi", min: &min, max: &max, want: "10Gi"},
+		{name: "above max", q: "2Ti", min: &min, max: &max, want: "1Ti"},
+		{name: "mixed units", q: "5000Mi", min: &min, max: &max, want: "10Gi"},
+		{name: "no bounds", q: "2Ti", want: "2Ti"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := quotaArith{}.clamp(resource.MustParse(tt.q), tt.min, tt.max)
+			if got.String() != tt.want {
+				t.Errorf("clamp() = %s, want %s", got.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// listQuotas lists the quotas of --dynamic-resource through the dynamic client with --dynamic,
+// and the core resourcequotas through the typed client otherwise.
+func (c *Config) listQuotas(ctx context.Context, namespace string, opts metav1.ListOptions) (*corev1.ResourceQuotaList, error) {
+	if c.dynamicClient == nil {
+		return c.client.CoreV1().ResourceQuotas(namespace).List(ctx, opts)
+	}
+
+	list, err := c.dynamicClient.Resource(c.quotaResource).Namespace(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	quotas := &corev1.ResourceQuotaList{}
+	quotas.ResourceVersion = list.GetResourceVersion()
+	quotas.Continue = list.GetContinue()
+	for i := range list.Items {
+		rq, err := fromUnstructured(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		quotas.Items = append(quotas.Items, *rq)
+	}
+	return quotas, nil
+}
+
+// getQuota gets one quota the same way as listQuotas.
+func (c *Config) getQuota(ctx context.Context, namespace, name string) (*corev1.ResourceQuota, error) {
+	if c.dynamicClient == nil {
+		return c.client.CoreV1().ResourceQuotas(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+
+	u, err := c.dynamicClient.Resource(c.quotaResource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return fromUnstructured(u)
+}
+
+// watchQuotas watches the quotas the same way as listQuotas. The dynamic watch is converted
+// so that callers see *corev1.ResourceQuota objects and *metav1.Status errors in both cases.
+func (c *Config) watchQuotas(ctx context.Context, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	if c.dynamicClient == nil {
+		return c.client.CoreV1().ResourceQuotas(namespace).Watch(ctx, opts)
+	}
+
+	w, err := c.dynamicClient.Resource(c.quotaResource).Namespace(namespace).Watch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+		u, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			return event, true
+		}
+		if event.Type == watch.Error {
+			if statusErr, ok := apierrors.FromObject(u).(*apierrors.StatusError); ok {
+				event.Object = &statusErr.ErrStatus
+			}
+			return event, true
+		}
+		rq, err := fromUnstructured(u)
+		if err != nil {
+			status := apierrors.NewInternalError(err).Status()
+			return watch.Event{Type: watch.Error, Object: &status}, true
+		}
+		event.Object = rq
+		return event, true
+	}), nil
+}
+
+// fromUnstructured converts a ResourceQuota-like object into a ResourceQuota. Only the
+// metadata and spec.hard are used by the tool, fields unknown to ResourceQuota are dropped.
+func fromUnstructured(u *unstructured.Unstructured) (*corev1.ResourceQuota, error) {
+	rq := &corev1.ResourceQuota{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), rq); err != nil {
+		return nil, err
+	}
+	return rq, nil
+}
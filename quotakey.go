@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// buildQuotaKey renders the quota key of class and suffix, for example
+// "rbd.storageclass.storage.k8s.io/requests.storage" with the default format.
+func buildQuotaKey(format, class, suffix string) string {
+	return fmt.Sprintf(format, class, suffix)
+}
+
+// quotaKeyFormatFor returns the quota key format with apiVersion as the group
+// portion, for example storageclass.storage.k8s.io.
+func quotaKeyFormatFor(apiVersion string) string {
+	return "%s." + apiVersion + "/%s"
+}
+
+// parseQuotaKey is the inverse of buildQuotaKey: it splits key back into the
+// storageclass name and resource suffix. ok is false when key does not match
+// format or either part is empty.
+func parseQuotaKey(format, key string) (class, suffix string, ok bool) {
+	parts := strings.Split(format, "%s")
+	if len(parts) != 3 {
+		return "", "", false
+	}
+	prefix, middle, trailer := unescapePercent(parts[0]), unescapePercent(parts[1]), unescapePercent(parts[2])
+
+	if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, trailer) || len(key) < len(prefix)+len(trailer) {
+		return "", "", false
+	}
+	rest := key[len(prefix) : len(key)-len(trailer)]
+
+	i := strings.Index(rest, middle)
+	if middle == "" || i < 0 {
+		return "", "", false
+	}
+	class, suffix = rest[:i], rest[i+len(middle):]
+	if class == "" || suffix == "" {
+		return "", "", false
+	}
+
+	return class, suffix, true
+}
+
+func unescapePercent(s string) string {
+	return strings.ReplaceAll(s, "%%", "%")
+}
+
+// countFormatVerbs returns the number of %s verbs in format, ignoring escaped %%.
+// Any other verb makes the format invalid and -1 is returned.
+func countFormatVerbs(format string) int {
+	n := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 >= len(format) {
+			return -1
+		}
+		i++
+		switch format[i] {
+		case '%':
+		case 's':
+			n++
+		default:
+			return -1
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,104 @@
+package main
+
+import "testing"
+
+func TestBuildQuotaKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		class  string
+		suffix string
+		want   string
+	}{
+		{name: "default", format: defaultQuotaKeyFormat, class: "rbd", suffix: requestsStorageSuffix, want: "rbd.storageclass.storage.k8s.io/requests.storage"},
+		{name: "dotted class", format: defaultQuotaKeyFormat, class: "ceph.rbd.fast", suffix: "persistentvolumeclaims", want: "ceph.rbd.fast.storageclass.storage.k8s.io/persistentvolumeclaims"},
+		{name: "empty suffix", format: defaultQuotaKeyFormat, class: "rbd", suffix: "", want: "rbd.storageclass.storage.k8s.io/"},
+		{name: "escaped percent", format: "%s.100%%/%s", class: "rbd", suffix: requestsStorageSuffix, want: "rbd.100%/requests.storage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildQuotaKey(tt.format, tt.class, tt.suffix); got != tt.want {
+				t.Errorf("buildQuotaKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQuotaKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		key    string
+		class  string
+		suffix string
+		ok     bool
+	}{
+		{name: "default", format: defaultQuotaKeyFormat, key: "rbd.storageclass.storage.k8s.io/requests.storage", class: "rbd", suffix: requestsStorageSuffix, ok: true},
+		{name: "dotted class", format: defaultQuotaKeyFormat, key: "ceph.rbd.fast.storageclass.storage.k8s.io/requests.storage", class: "ceph.rbd.fast", suffix: requestsStorageSuffix, ok: true},
+		{name: "empty suffix", format: defaultQuotaKeyFormat, key: "rbd.storageclass.storage.k8s.io/", ok: false},
+		{name: "empty class", format: defaultQuotaKeyFormat, key: ".storageclass.storage.k8s.io/requests.storage", ok: false},
+		{name: "generic key", format: defaultQuotaKeyFormat, key: "requests.storage", ok: false},
+		{name: "other group", format: defaultQuotaKeyFormat, key: "rbd.storageclass.example.com/requests.storage", ok: false},
+		{name: "escaped percent", format: "%s.100%%/%s", key: "rbd.100%/requests.storage", class: "rbd", suffix: requestsStorageSuffix, ok: true},
+		{name: "invalid format", format: "%s/requests.storage", key: "rbd/requests.storage", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, suffix, ok := parseQuotaKey(tt.format, tt.key)
+			if ok != tt.ok || class != tt.class || suffix != tt.suffix {
+				t.Errorf("parseQuotaKey() = (%q, %q, %v), want (%q, %q, %v)", class, suffix, ok, tt.class, tt.suffix, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseQuotaKeyRoundTrip(t *testing.T) {
+	for _, class := range []string{"rbd", "ceph.rbd.fast", "local-path"} {
+		key := buildQuotaKey(defaultQuotaKeyFormat, class, requestsStorageSuffix)
+		got, suffix, ok := parseQuotaKey(defaultQuotaKeyFormat, key)
+		if !ok || got != class || suffix != requestsStorageSuffix {
+			t.Errorf("parseQuotaKey(buildQuotaKey(%q)) = (%q, %q, %v)", class, got, suffix, ok)
+		}
+	}
+}
+
+func TestCountFormatVerbs(t *testing.T) {
+	tests := []struct {
+		format string
+		want   int
+	}{
+		{format: defaultQuotaKeyFormat, want: 2},
+		{format: "%s.100%%/%s", want: 2},
+		{format: "%s/requests.storage", want: 1},
+		{format: "%d.storageclass/%s", want: -1},
+		{format: "%s/%", want: -1},
+	}
+	for _, tt := range tests {
+		if got := countFormatVerbs(tt.format); got != tt.want {
+			t.Errorf("countFormatVerbs(%q) = %d, want %d", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestQuotaKeyFormatFor(t *testing.T) {
+	tests := []struct {
+		apiVersion string
+		want       string
+	}{
+		{apiVersion: defaultStorageclassAPIVersion, want: "rbd.storageclass.storage.k8s.io/requests.storage"},
+		{apiVersion: "storageclass.storage.k8s.io.v2", want: "rbd.storageclass.storage.k8s.io.v2/requests.storage"},
+		{apiVersion: "storageclass.example.com", want: "rbd.storageclass.example.com/requests.storage"},
+	}
+	for _, tt := range tests {
+		format := quotaKeyFormatFor(tt.apiVersion)
+		if got := buildQuotaKey(format, "rbd", requestsStorageSuffix); got != tt.want {
+			t.Errorf("buildQuotaKey(quotaKeyFormatFor(%q)) = %q, want %q", tt.apiVersion, got, tt.want)
+		}
+		if class, suffix, ok := parseQuotaKey(format, tt.want); !ok || class != "rbd" || suffix != requestsStorageSuffix {
+			t.Errorf("parseQuotaKey(quotaKeyFormatFor(%q), %q) = (%q, %q, %v)", tt.apiVersion, tt.want, class, suffix, ok)
+		}
+	}
+	if quotaKeyFormatFor(defaultStorageclassAPIVersion) != defaultQuotaKeyFormat {
+		t.Errorf("quotaKeyFormatFor(%q) = %q, want %q", defaultStorageclassAPIVersion, quotaKeyFormatFor(defaultStorageclassAPIVersion), defaultQuotaKeyFormat)
+	}
+}
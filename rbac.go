@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// CheckPermissions asks the apiserver whether the current identity can perform
+// every request the tool is going to issue, so that missing permissions are
+// reported up front instead of as scattered forbidden errors.
+func (c *Config) CheckPermissions() {
+	attributes := []authorizationv1.ResourceAttributes{
+		{Verb: "list", Group: c.quotaResource.Group, Resource: c.quotaResource.Resource, Namespace: c.namespace},
+	}
+	if c.targetName != "" {
+		attributes[0] = authorizationv1.ResourceAttributes{Verb: "get", Group: c.quotaResource.Group, Resource: c.quotaResource.Resource, Namespace: c.targetNamespace, Name: c.targetName}
+	}
+	if c.storageclassPattern != "" || c.action == "list-storageclasses" || c.action == "orphans" {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "list", Group: "storage.k8s.io", Resource: "storageclasses"})
+	} else if c.storageclass != "" && !c.skipSCCheck {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "get", Group: "storage.k8s.io", Resource: "storageclasses", Name: c.storageclass})
+	}
+	if len(c.namespaces) != 0 {
+		attributes = attributes[1:]
+		for _, ns := range c.namespaces {
+			attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "list", Group: c.quotaResource.Group, Resource: c.quotaResource.Resource, Namespace: ns})
+		}
+	}
+	patches := c.mutates() && !c.countOnly
+	if patches && len(c.namespaces) != 0 {
+		for _, ns := range c.namespaces {
+			attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "patch", Group: c.quotaResource.Group, Resource: c.quotaResource.Resource, Namespace: ns})
+		}
+	} else if patches {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "patch", Group: c.quotaResource.Group, Resource: c.quotaResource.Resource, Namespace: c.namespace})
+	}
+	if patches && !c.dryRun && (c.reason != "" || c.ticket != "") && c.quotaResource == resourceQuotaGVR {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "create", Resource: "events", Namespace: c.namespace})
+	}
+	if c.groupByLabel != "" || (c.action == "audit-missing" && c.namespace == metav1.NamespaceAll && len(c.namespaces) == 0) {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "list", Resource: "namespaces"})
+	}
+	if namespace, name, ok := parseNamespacedName(c.resultConfigMap); ok {
+		attributes = append(attributes,
+			authorizationv1.ResourceAttributes{Verb: "get", Resource: "configmaps", Namespace: namespace, Name: name},
+			authorizationv1.ResourceAttributes{Verb: "create", Resource: "configmaps", Namespace: namespace},
+			authorizationv1.ResourceAttributes{Verb: "update", Resource: "configmaps", Namespace: namespace, Name: name},
+		)
+	}
+	if c.enableLeaderElection && patches {
+		attributes = append(attributes,
+			authorizationv1.ResourceAttributes{Verb: "get", Group: "coordination.k8s.io", Resource: "leases", Namespace: c.leaseNamespace, Name: c.leaseName},
+			authorizationv1.ResourceAttributes{Verb: "create", Group: "coordination.k8s.io", Resource: "leases", Namespace: c.leaseNamespace},
+			authorizationv1.ResourceAttributes{Verb: "update", Group: "coordination.k8s.io", Resource: "leases", Namespace: c.leaseNamespace, Name: c.leaseName},
+		)
+	}
+	if c.action == "sync" {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "list", Group: c.quotaResource.Group, Resource: c.quotaResource.Resource, Namespace: c.templateNamespace})
+		if c.createIfMissing {
+			attributes = append(attributes,
+				authorizationv1.ResourceAttributes{Verb: "create", Group: c.quotaResource.Group, Resource: c.quotaResource.Resource, Namespace: c.namespace},
+				authorizationv1.ResourceAttributes{Verb: "list", Resource: "namespaces"},
+			)
+		}
+	}
+	if c.action == "import" && c.createIfMissing {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "create", Group: c.quotaResource.Group, Resource: c.quotaResource.Resource, Namespace: c.namespace})
+	}
+	if c.sinceResourceVersion != "" && c.targetName == "" {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "watch", Group: c.quotaResource.Group, Resource: c.quotaResource.Resource, Namespace: c.namespace})
+	}
+	if c.onlyWithPVCs || c.onlyIfUnused {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "list", Resource: "persistentvolumeclaims", Namespace: c.namespace})
+	}
+
+	var errorList []error
+	for i := range attributes {
+		if err := c.checkPermission(c.client, &attributes[i]); err != nil {
+			errorList = append(errorList, err)
+		}
+	}
+
+	if len(errorList) != 0 {
+		c.fatalf("rbac self-check failed (use --skip-rbac-check to bypass): %v", utilerrors.NewAggregate(errorList))
+	}
+}
+
+// CheckImpersonation verifies with the caller's own identity that it may impersonate the requested user, groups and uid.
+func (c *Config) CheckImpersonation(client kubernetes.Interface) {
+	var attributes []authorizationv1.ResourceAttributes
+	if c.asUser != "" {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "impersonate", Resource: "users", Name: c.asUser})
+	}
+	for _, group := range c.asGroups {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "impersonate", Resource: "groups", Name: group})
+	}
+	if c.asUID != "" {
+		attributes = append(attributes, authorizationv1.ResourceAttributes{Verb: "impersonate", Group: "authentication.k8s.io", Resource: "uids", Name: c.asUID})
+	}
+
+	var errorList []error
+	for i := range attributes {
+		if err := c.checkPermission(client, &attributes[i]); err != nil {
+			errorList = append(errorList, err)
+		}
+	}
+
+	if len(errorList) != 0 {
+		c.fatalf("impersonation self-check failed (use --skip-rbac-check to bypass): %v", utilerrors.NewAggregate(errorList))
+	}
+}
+
+func (c *Config) checkPermission(client kubernetes.Interface, attr *authorizationv1.ResourceAttributes) error {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attr},
+	}
+	resp, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(c.context, review, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("error happened when checking permission %s: %v", describeAttributes(attr), err)
+	}
+	if !resp.Status.Allowed {
+		return fmt.Errorf("missing permission %s", describeAttributes(attr))
+	}
+
+	klog.V(4).Infof("permission %s allowed", describeAttributes(attr))
+	return nil
+}
+
+func describeAttributes(attr *authorizationv1.ResourceAttributes) string {
+	resource := attr.Resource
+	if attr.Group != "" {
+		resource = attr.Resource + "." + attr.Group
+	}
+	if attr.Name != "" {
+		resource = resource + "/" + attr.Name
+	}
+	if attr.Namespace == "" {
+		return fmt.Sprintf("%s %s (cluster-wide)", attr.Verb, resource)
+	}
+
+	return fmt.Sprintf("%s %s in namespace/%s", attr.Verb, resource, attr.Namespace)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+// reconcile gets every ResourceQuota patched in the run again once the run is
+// over and checks that its storageclass keys still have the value the run set,
+// for --reconcile. It catches what a mutating webhook or another client changed
+// after the patch. Every mismatch is an error.
+func (c *Config) reconcile(results []Result) error {
+	byQuota := map[string][]Result{}
+	var ids []string
+	for _, r := range results {
+		if r.Status != StatusPatched || r.StorageClass == "" {
+			continue
+		}
+		id := r.Namespace + "/" + r.Quota
+		if _, ok := byQuota[id]; !ok {
+			ids = append(ids, id)
+		}
+		byQuota[id] = append(byQuota[id], r)
+	}
+	sort.Strings(ids)
+
+	var errorList []error
+	checked := 0
+	for _, id := range ids {
+		expected := byQuota[id]
+		namespace, name := expected[0].Namespace, expected[0].Quota
+		ctx, cancel := c.requestContext()
+		rq, err := c.getQuota(ctx, namespace, name)
+		c.checkRequestTimeout(ctx, fmt.Sprintf("get resourcequota/%s in namespace/%s", name, namespace))
+		cancel()
+		if err != nil {
+			klog.Errorf("failed to reconcile resourcequota/%s in namespace/%s: %v", name, namespace, err)
+			errorList = append(errorList, fmt.Errorf("reconcile resourcequota/%s in namespace/%s: %v", name, namespace, err))
+			continue
+		}
+		for _, r := range expected {
+			checked++
+			if err := c.reconcileKey(*rq, r); err != nil {
+				klog.Errorf("reconcile: %v", err)
+				errorList = append(errorList, err)
+			}
+		}
+	}
+
+	if len(errorList) == 0 {
+		klog.Infof("reconcile: %d storageclass quotas in %d resourcequotas match the applied values", checked, len(ids))
+	} else {
+		klog.Errorf("reconcile: %d of %d storageclass quotas do not match the applied values", len(errorList), checked)
+	}
+	return utilerrors.NewAggregate(errorList)
+}
+
+// reconcileKey checks the storageclass key of r in rq against the value r applied,
+// an empty New meaning the key was removed.
+func (c *Config) reconcileKey(rq corev1.ResourceQuota, r Result) error {
+	actual, ok := hardValue(rq, c.quotaKey(r.StorageClass))
+	if r.New == "" {
+		if ok {
+			return fmt.Errorf("storageclass/%s limits of resourcequota/%s in namespace/%s were removed but are %s", r.StorageClass, rq.Name, rq.Namespace, actual.String())
+		}
+		return nil
+	}
+
+	want, err := resource.ParseQuantity(r.New)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("storageclass/%s limits of resourcequota/%s in namespace/%s were set to %s but are not set", r.StorageClass, rq.Name, rq.Namespace, r.New)
+	}
+	if !quantitiesEqual(actual, want) {
+		return fmt.Errorf("storageclass/%s limits of resourcequota/%s in namespace/%s were set to %s but are %s", r.StorageClass, rq.Name, rq.Namespace, r.New, actual.String())
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// StatusReported is the status of the results of the report action.
+const StatusReported = "reported"
+
+// distributionBuckets are the upper bounds of the --distribution buckets, a
+// value falls into the first bucket whose bound it is below and into
+// distributionAbove otherwise.
+const distributionAbove = ">100Gi"
+
+var distributionBuckets = []struct {
+	label string
+	below resource.Quantity
+}{
+	{"<10Gi", resource.MustParse("10Gi")},
+	{"10Gi-100Gi", resource.MustParse("100Gi")},
+}
+
+// ReportStorageclassQuotas returns the current requests.storage quota of every
+// target storageclass in every ResourceQuota in scope. It never mutates anything.
+func (c *Config) ReportStorageclassQuotas() ([]Result, error) {
+	var results []Result
+	err := c.forEachResourceQuotaPage(func(rqs []corev1.ResourceQuota) error {
+		for _, rq := range rqs {
+			for _, class := range c.storageclasses {
+				result := Result{Namespace: rq.Namespace, Quota: rq.Name, StorageClass: class, Action: c.action, Status: StatusReported}
+				if q, ok := hardValue(rq, c.quotaKey(class)); ok {
+					result.Old = q.String()
+				}
+				results = append(results, result)
+			}
+		}
+		return nil
+	})
+
+	return results, err
+}
+
+// printDistribution writes a histogram of the reported quota values of every
+// storageclass to out.
+func (c *Config) printDistribution(out io.Writer, results []Result) {
+	for _, class := range c.storageclasses {
+		labels := []string{"unset"}
+		for _, b := range distributionBuckets {
+			labels = append(labels, b.label)
+		}
+		labels = append(labels, distributionAbove)
+		counts := make(map[string]int, len(labels))
+
+		for _, r := range results {
+			if r.StorageClass != class {
+				continue
+			}
+			counts[distributionBucket(r.Old)]++
+		}
+
+		fmt.Fprintf(out, "storageclass/%s\n", class)
+		for _, label := range labels {
+			fmt.Fprintf(out, "  %-12s %5d %s\n", label, counts[label], strings.Repeat("#", counts[label]))
+		}
+	}
+}
+
+func distributionBucket(value string) string {
+	if value == "" {
+		return "unset"
+	}
+	q := resource.MustParse(value)
+	for _, b := range distributionBuckets {
+		if q.Cmp(b.below) < 0 {
+			return b.label
+		}
+	}
+	return distributionAbove
+}
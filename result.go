@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Result status values.
+const (
+	StatusPatched = "patched"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+	StatusPlanned = "planned"
+	// StatusRolledBack is a patch reverted by --transactional after the run aborted.
+	StatusRolledBack = "rolled-back"
+)
+
+// Result records what happened to a single storageclass key of a ResourceQuota during a run.
+type Result struct {
+	Namespace    string `json:"namespace"`
+	Quota        string `json:"quota"`
+	StorageClass string `json:"storageclass,omitempty"`
+	Action       string `json:"action"`
+	Old          string `json:"old,omitempty"`
+	New          string `json:"new,omitempty"`
+	Change       string `json:"change,omitempty"`
+	Status       string `json:"status"`
+	Message      string `json:"message,omitempty"`
+}
+
+// relativeChange renders the change from the old to the new value as a percentage,
+// such as +20% or -100%. It is empty when either side is unlimited and n/a when
+// the old value is 0.
+func relativeChange(from, to string) string {
+	if from == "" || to == "" {
+		return ""
+	}
+	o, err := resource.ParseQuantity(from)
+	if err != nil {
+		return ""
+	}
+	n, err := resource.ParseQuantity(to)
+	if err != nil {
+		return ""
+	}
+	if o.IsZero() {
+		if n.IsZero() {
+			return "+0%"
+		}
+		return "n/a"
+	}
+
+	of, _ := strconv.ParseFloat(o.AsDec().String(), 64)
+	nf, _ := strconv.ParseFloat(n.AsDec().String(), 64)
+	return fmt.Sprintf("%+.0f%%", (nf-of)/of*100)
+}
+
+// plannedTotals sums up the planned results of a dry-run: how many namespaces
+// would change, the total of the new limits, how many would be set to 0 or
+// removed, and the net change where both the old and the new limit are set.
+func plannedTotals(results []Result) string {
+	namespaces := map[string]bool{}
+	total := resource.NewQuantity(0, resource.BinarySI)
+	net := resource.NewQuantity(0, resource.BinarySI)
+	zeroed, removed := 0, 0
+	for _, r := range results {
+		if r.Status != StatusPlanned {
+			continue
+		}
+		namespaces[r.Namespace] = true
+		if r.New == "" {
+			removed++
+			continue
+		}
+		n := resource.MustParse(r.New)
+		if n.IsZero() {
+			zeroed++
+		}
+		total.Add(n)
+		if r.Old != "" {
+			net.Add(n)
+			net.Sub(resource.MustParse(r.Old))
+		}
+	}
+
+	sign := "+"
+	if net.Sign() < 0 {
+		sign = ""
+	}
+	return fmt.Sprintf("%d namespaces would change: %s in new limits, %s%s net change, %d set to 0, %d removed",
+		len(namespaces), total.String(), sign, net.String(), zeroed, removed)
+}
+
+// summarize counts results by status.
+func summarize(results []Result) string {
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+
+	if counts[StatusPlanned] != 0 {
+		return fmt.Sprintf("%d storageclass quotas processed: %d planned, %d skipped, %d failed",
+			len(results), counts[StatusPlanned], counts[StatusSkipped], counts[StatusFailed])
+	}
+	if counts[StatusRolledBack] != 0 {
+		return fmt.Sprintf("%d storageclass quotas processed: %d patched, %d rolled back, %d skipped, %d failed",
+			len(results), counts[StatusPatched], counts[StatusRolledBack], counts[StatusSkipped], counts[StatusFailed])
+	}
+	return fmt.Sprintf("%d storageclass quotas processed: %d patched, %d skipped, %d failed",
+		len(results), counts[StatusPatched], counts[StatusSkipped], counts[StatusFailed])
+}
+
+// Summary is the machine-readable outcome of a run written by --summary-json.
+type Summary struct {
+	Action          string         `json:"action"`
+	StorageClasses  []string       `json:"storageclasses,omitempty"`
+	Namespace       string         `json:"namespace"`
+	Reason          string         `json:"reason,omitempty"`
+	Ticket          string         `json:"ticket,omitempty"`
+	StartedAt       time.Time      `json:"startedAt"`
+	Duration        string         `json:"duration"`
+	ResourceVersion string         `json:"resourceVersion,omitempty"`
+	Counts          map[string]int `json:"counts"`
+	Results         []Result       `json:"results"`
+	Error           string         `json:"error,omitempty"`
+}
+
+func (c *Config) newSummary(results []Result, errText string) Summary {
+	summary := Summary{
+		Action:          c.action,
+		StorageClasses:  c.storageclasses,
+		Namespace:       c.namespace,
+		Reason:          c.reason,
+		Ticket:          c.ticket,
+		StartedAt:       c.startedAt,
+		Duration:        time.Since(c.startedAt).Round(time.Millisecond).String(),
+		ResourceVersion: c.currentResourceVersion,
+		Counts:          map[string]int{},
+		Results:         sortResults(results, c.outputSort),
+	}
+	if summary.Namespace == metav1.NamespaceAll {
+		summary.Namespace = "*"
+	} else if alias, ok := c.redactions[summary.Namespace]; ok && c.redacted {
+		summary.Namespace = alias
+	}
+	for _, r := range results {
+		summary.Counts[r.Status]++
+	}
+	summary.Error = errText
+
+	return summary
+}
+
+// writeSummaryJSON writes the summary of the run to path.
+func writeSummaryJSON(path string, summary Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
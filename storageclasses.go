@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// StorageclassUsage describes a StorageClass and how much quota is allocated to it
+// across the ResourceQuotas in scope.
+type StorageclassUsage struct {
+	Name        string `json:"name"`
+	Provisioner string `json:"provisioner"`
+	Quotas      int    `json:"quotas"`
+	Total       string `json:"total"`
+}
+
+// ListStorageclassUsage lists every StorageClass with the number of ResourceQuotas
+// that restrict it and the sum of their requests.storage limits. It never mutates anything.
+func (c *Config) ListStorageclassUsage() ([]StorageclassUsage, error) {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	scs, err := c.client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	c.checkRequestTimeout(ctx, "list storageclasses")
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]*resource.Quantity, len(scs.Items))
+	counts := make(map[string]int, len(scs.Items))
+	for _, sc := range scs.Items {
+		totals[sc.Name] = resource.NewQuantity(0, resource.BinarySI)
+	}
+	err = c.forEachResourceQuotaPage(func(rqs []corev1.ResourceQuota) error {
+		for _, rq := range rqs {
+			for class, total := range totals {
+				if q, ok := hardValue(rq, c.quotaKey(class)); ok {
+					total.Add(q)
+					counts[class]++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usages := make([]StorageclassUsage, 0, len(scs.Items))
+	for _, sc := range scs.Items {
+		usages = append(usages, StorageclassUsage{
+			Name:        sc.Name,
+			Provisioner: sc.Provisioner,
+			Quotas:      counts[sc.Name],
+			Total:       totals[sc.Name].String(),
+		})
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Name < usages[j].Name })
+
+	return usages, nil
+}
+
+// printStorageclassUsage writes usages to stdout in the --output format.
+func (c *Config) printStorageclassUsage(usages []StorageclassUsage) error {
+	switch c.output {
+	case outputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(usages)
+	case outputTable, outputWide:
+		return printStorageclassTable(os.Stdout, usages)
+	}
+
+	for _, u := range usages {
+		klog.Infof("storageclass/%s (%s) is restricted by %d resourcequotas, %s in total", u.Name, u.Provisioner, u.Quotas, u.Total)
+	}
+	return nil
+}
+
+func printStorageclassTable(out io.Writer, usages []StorageclassUsage) error {
+	w := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(w, "STORAGECLASS\tPROVISIONER\tQUOTAS\tTOTAL")
+	for _, u := range usages {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", u.Name, u.Provisioner, u.Quotas, u.Total)
+	}
+
+	return w.Flush()
+}
+
+// StorageclassDetails is the part of a StorageClass printed by --print-storageclass.
+type StorageclassDetails struct {
+	Name                 string            `json:"name"`
+	Provisioner          string            `json:"provisioner"`
+	ReclaimPolicy        string            `json:"reclaimPolicy,omitempty"`
+	VolumeBindingMode    string            `json:"volumeBindingMode,omitempty"`
+	AllowVolumeExpansion bool              `json:"allowVolumeExpansion"`
+	Parameters           map[string]string `json:"parameters,omitempty"`
+}
+
+// printStorageclassDetails writes the resolved storage classes to stdout as JSON.
+func printStorageclassDetails(scs []storagev1.StorageClass) error {
+	details := make([]StorageclassDetails, 0, len(scs))
+	for _, sc := range scs {
+		details = append(details, storageclassDetails(sc))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(details)
+}
+
+func storageclassDetails(sc storagev1.StorageClass) StorageclassDetails {
+	d := StorageclassDetails{
+		Name:        sc.Name,
+		Provisioner: sc.Provisioner,
+		Parameters:  sc.Parameters,
+	}
+	if sc.ReclaimPolicy != nil {
+		d.ReclaimPolicy = string(*sc.ReclaimPolicy)
+	}
+	if sc.VolumeBindingMode != nil {
+		d.VolumeBindingMode = string(*sc.VolumeBindingMode)
+	}
+	if sc.AllowVolumeExpansion != nil {
+		d.AllowVolumeExpansion = *sc.AllowVolumeExpansion
+	}
+
+	return d
+}
+
+// storageclassDetails returns the details of the StorageClasses cached during
+// validation, for --output wide. Storage classes that were not fetched, with
+// --skip-sc-check or by the sync action, show as <none>.
+func (c *Config) storageclassDetails() map[string]StorageclassDetails {
+	details := make(map[string]StorageclassDetails, len(c.storageclassObjects))
+	for name, sc := range c.storageclassObjects {
+		details[name] = storageclassDetails(sc)
+	}
+
+	return details
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+)
+
+// loadTemplate reads the requests.storage quota of every storageclass from the
+// ResourceQuotas of --template-namespace, which the sync action then applies to
+// every other namespace in scope. A storageclass limited differently by two
+// template ResourceQuotas is an error.
+func (c *Config) loadTemplate() {
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	rqs, err := c.listQuotas(ctx, c.templateNamespace, c.quotaListOptions())
+	c.checkRequestTimeout(ctx, "list resourcequotas in namespace/"+c.templateNamespace)
+	if err != nil {
+		c.fatalf("error happened when list resourcequotas of template namespace/%s,error: %v", c.templateNamespace, err.Error())
+	}
+	if len(rqs.Items) == 0 {
+		c.fatalf("template namespace/%s has no resourcequota", c.templateNamespace)
+	}
+
+	c.templateQuotas = map[string]resource.Quantity{}
+	c.templateQuotaName = rqs.Items[0].Name
+	for _, rq := range rqs.Items {
+		for name, q := range hardLimits(rq) {
+			class, suffix, ok := parseQuotaKey(c.keyFormat, string(name))
+			if !ok || suffix != requestsStorageSuffix {
+				continue
+			}
+			if existing, ok := c.templateQuotas[class]; ok && !quantitiesEqual(existing, q) {
+				c.fatalf("template namespace/%s limits storageclass/%s to both %s and %s", c.templateNamespace, class, existing.String(), q.String())
+			}
+			c.templateQuotas[class] = q
+		}
+	}
+	if len(c.templateQuotas) == 0 {
+		c.fatalf("template namespace/%s has no storageclass quota", c.templateNamespace)
+	}
+
+	c.storageclasses = nil
+	for class := range c.templateQuotas {
+		c.storageclasses = append(c.storageclasses, class)
+	}
+	sort.Strings(c.storageclasses)
+	for _, class := range c.storageclasses {
+		q := c.templateQuotas[class]
+		klog.Infof("template namespace/%s limits storageclass/%s to %s", c.templateNamespace, class, q.String())
+	}
+}
+
+// createMissingQuotas creates a ResourceQuota with the template storageclass
+// quotas in every namespace in scope that has none, for --create-if-missing.
+func (c *Config) createMissingQuotas() ([]Result, error) {
+	missing, err := c.NamespacesWithoutQuota()
+	if err != nil {
+		return nil, err
+	}
+
+	hard := corev1.ResourceList{}
+	for class, q := range c.templateQuotas {
+		hard[corev1.ResourceName(c.quotaKey(class))] = q.DeepCopy()
+	}
+
+	var results []Result
+	var errorList []error
+	for _, ns := range missing {
+		result, err := c.createQuota(ns, c.templateQuotaName, hard)
+		results = append(results, result)
+		if err != nil {
+			errorList = append(errorList, err)
+			if c.exitOnFirstError {
+				break
+			}
+		}
+	}
+
+	return results, utilerrors.NewAggregate(errorList)
+}
+
+// createQuota creates a managed ResourceQuota with the hard limits in namespace,
+// or only logs it in dry-run.
+func (c *Config) createQuota(namespace, name string, hard corev1.ResourceList) (Result, error) {
+	rq := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: c.managedAnnotations(),
+		},
+		Spec: corev1.ResourceQuotaSpec{Hard: hard},
+	}
+	if c.labelKey != "" {
+		rq.Labels = map[string]string{c.labelKey: c.labelValue}
+	}
+	result := Result{Namespace: namespace, Quota: name, Action: c.action}
+	if c.dryRun {
+		klog.Infof("[dry-run] would create resourcequota/%s in namespace/%s", name, namespace)
+		result.Status = StatusPlanned
+		result.Message = "dry-run, would create"
+		return result, nil
+	}
+
+	ctx, cancel := c.requestContext()
+	defer cancel()
+	_, err := c.client.CoreV1().ResourceQuotas(namespace).Create(ctx, rq, metav1.CreateOptions{FieldManager: fieldManager})
+	c.checkRequestTimeout(ctx, fmt.Sprintf("create resourcequota/%s in namespace/%s", name, namespace))
+	if err != nil {
+		klog.Warningf("failed to create resourcequota/%s in namespace/%s: %v", name, namespace, err)
+		result.Status = StatusFailed
+		result.Message = err.Error()
+		return result, err
+	}
+	klog.V(2).Infof("successful created resourcequota/%s in namespace/%s", name, namespace)
+	result.Status = StatusPatched
+	result.Message = "created"
+
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// timings collects the latency of every patch request when --timing is set.
+type timings struct {
+	start     time.Time
+	latencies []time.Duration
+}
+
+func newTimings() *timings {
+	return &timings{start: time.Now()}
+}
+
+// observe is a no-op on a nil receiver so callers need not check --timing.
+func (t *timings) observe(d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.latencies = append(t.latencies, d)
+}
+
+func (t *timings) String() string {
+	total := time.Since(t.start).Round(time.Millisecond)
+	if len(t.latencies) == 0 {
+		return fmt.Sprintf("no patch issued, total %v", total)
+	}
+
+	sorted := append([]time.Duration(nil), t.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) time.Duration {
+		i := int(p*float64(len(sorted))+0.5) - 1
+		if i < 0 {
+			i = 0
+		}
+		if i >= len(sorted) {
+			i = len(sorted) - 1
+		}
+		return sorted[i]
+	}
+
+	return fmt.Sprintf("%d patches: min %v, p50 %v, p90 %v, p99 %v, max %v, total %v",
+		len(sorted), sorted[0], percentile(0.50), percentile(0.90), percentile(0.99), sorted[len(sorted)-1], total)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type usageExample struct {
+	description string
+	args        string
+}
+
+type usageText struct {
+	examples       []usageExample
+	exitCodes      string
+	auditExitCodes string
+	lintExitCodes  string
+}
+
+// usageTexts holds the help text for every supported --lang, keep them in sync.
+var usageTexts = map[string]usageText{
+	"zh": {
+		examples: []usageExample{
+			{"禁用prometheus对rbd-ceph-csi的使用", "add -s rbd-ceph-csi -n prometheus"},
+			{"允许prometheus对rbd-ceph-csi的使用", "remove -s rbd-ceph-csi -n prometheus"},
+			{"禁用所有命名空间对rbd-ceph-csi的使用", "add -s rbd-ceph-csi"},
+			{"将prometheus命名空间对rbd-ceph-csi的限额调整为50G", "add -s rbd-ceph-csi -n prometheus -q 50G"},
+			{"将template命名空间的存储类限额同步到所有命名空间", "sync --template-namespace template --create-if-missing"},
+			{"列出所有存储类及其限额总量", "list-storageclasses --output json"},
+		},
+		exitCodes:      "check 退出码: 0 与基线一致, 1 执行出错, 2 参数错误, 3 与基线不一致\n指定 --diff-exit-code 时: 0 与基线一致, 1 与基线不一致, 2 执行出错或参数错误",
+		auditExitCodes: "audit-missing 退出码: 0 所有命名空间都有ResourceQuota, 1 执行出错, 2 参数错误, 3 存在没有ResourceQuota的命名空间",
+		lintExitCodes:  "lint 退出码: 0 没有可疑的限额键, 1 执行出错, 2 参数错误, 3 发现可疑的限额键",
+	},
+	"en": {
+		examples: []usageExample{
+			{"Forbid prometheus from using rbd-ceph-csi", "add -s rbd-ceph-csi -n prometheus"},
+			{"Allow prometheus to use rbd-ceph-csi", "remove -s rbd-ceph-csi -n prometheus"},
+			{"Forbid all namespaces from using rbd-ceph-csi", "add -s rbd-ceph-csi"},
+			{"Limit prometheus to 50G of rbd-ceph-csi", "add -s rbd-ceph-csi -n prometheus -q 50G"},
+			{"Copy the storageclass quotas of the template namespace to all namespaces", "sync --template-namespace template --create-if-missing"},
+			{"List the storage classes with their total quota", "list-storageclasses --output json"},
+		},
+		exitCodes:      "check exit codes: 0 matches the baseline, 1 execution error, 2 invalid flags, 3 drift detected\nwith --diff-exit-code: 0 matches the baseline, 1 drift detected, 2 execution error or invalid flags",
+		auditExitCodes: "audit-missing exit codes: 0 every namespace has a ResourceQuota, 1 execution error, 2 invalid flags, 3 namespaces without a ResourceQuota found",
+		lintExitCodes:  "lint exit codes: 0 no suspicious quota keys, 1 execution error, 2 invalid flags, 3 suspicious quota keys found",
+	},
+}
+
+// usageLang returns the --lang value from the command line. The help text is
+// built before the flags are parsed, so the arguments are scanned directly.
+func usageLang(current string) string {
+	args := os.Args[1:]
+	for i, arg := range args {
+		if v := strings.TrimPrefix(arg, "--lang="); v != arg {
+			return v
+		}
+		if arg == "--lang" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	return current
+}
+
+// usageExamples renders the examples of lang for the help text.
+func usageExamples(lang string) string {
+	text, ok := usageTexts[lang]
+	if !ok {
+		text = usageTexts["zh"]
+	}
+
+	var b strings.Builder
+	for i, e := range text.examples {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "  # %s\n  %s %s", e.description, os.Args[0], e.args)
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// The replay of the changes since --since-resource-version ends once it reaches
+// the resourceVersion listed at its start, or once the apiserver has sent nothing
+// for watchIdleTimeout, which is when nothing changed since then. It never takes
+// longer than watchReplayDeadline.
+const (
+	watchIdleTimeout    = 5 * time.Second
+	watchReplayDeadline = time.Minute
+)
+
+// errResourceVersionGone means --since-resource-version is older than what the
+// apiserver still keeps, and errReplayIncomplete that the replay did not reach
+// the current resourceVersion in time. The caller then falls back to a full list.
+var (
+	errResourceVersionGone = errors.New("resource version is too old")
+	errReplayIncomplete    = errors.New("replay did not reach the current resource version")
+)
+
+// changedResourceQuotas returns the ResourceQuotas in scope that were added or
+// modified after --since-resource-version, in their latest state, and the current
+// resourceVersion to pass to the next run. ResourceQuotas deleted since then are
+// left out.
+func (c *Config) changedResourceQuotas() ([]corev1.ResourceQuota, string, error) {
+	ctx, cancel := c.requestContext()
+	opts := c.quotaListOptions()
+	opts.Limit = 1
+	list, err := c.listQuotas(ctx, c.namespace, opts)
+	c.checkRequestTimeout(ctx, "list resourcequotas")
+	cancel()
+	if err != nil {
+		return nil, "", err
+	}
+	current := list.ResourceVersion
+
+	opts = c.quotaListOptions()
+	opts.ResourceVersion = c.sinceResourceVersion
+	opts.AllowWatchBookmarks = true
+	timeoutSeconds := int64(watchReplayDeadline / time.Second)
+	opts.TimeoutSeconds = &timeoutSeconds
+	ctx, cancel = context.WithTimeout(c.context, watchReplayDeadline)
+	defer cancel()
+	w, err := c.watchQuotas(ctx, c.namespace, opts)
+	if err != nil {
+		if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+			return nil, "", errResourceVersionGone
+		}
+		return nil, "", err
+	}
+	defer w.Stop()
+
+	latest := map[string]*corev1.ResourceQuota{}
+	var order []string
+	replayed := func() []corev1.ResourceQuota {
+		items := make([]corev1.ResourceQuota, 0, len(order))
+		for _, id := range order {
+			if rq := latest[id]; rq != nil {
+				items = append(items, *rq)
+			}
+		}
+		return items
+	}
+	for {
+		select {
+		case <-c.context.Done():
+			return nil, "", c.context.Err()
+		case <-ctx.Done():
+			return nil, "", errReplayIncomplete
+		case <-time.After(watchIdleTimeout):
+			return replayed(), current, nil
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				if ctx.Err() != nil {
+					return nil, "", errReplayIncomplete
+				}
+				return nil, "", fmt.Errorf("watch of resourcequotas closed before the changes since resourceVersion %s were replayed", c.sinceResourceVersion)
+			}
+			if event.Type == watch.Error {
+				if status, ok := event.Object.(*metav1.Status); ok && (status.Code == http.StatusGone || status.Reason == metav1.StatusReasonExpired) {
+					return nil, "", errResourceVersionGone
+				}
+				return nil, "", apierrors.FromObject(event.Object)
+			}
+			rq, ok := event.Object.(*corev1.ResourceQuota)
+			if !ok {
+				continue
+			}
+			if event.Type != watch.Bookmark {
+				id := rq.Namespace + "/" + rq.Name
+				if _, seen := latest[id]; !seen {
+					order = append(order, id)
+				}
+				if event.Type == watch.Deleted {
+					latest[id] = nil
+				} else {
+					latest[id] = rq
+				}
+			}
+			if reachedResourceVersion(rq.ResourceVersion, current) {
+				return replayed(), current, nil
+			}
+		}
+	}
+}
+
+// reachedResourceVersion reports whether the resourceVersion of an event is at or
+// past current. resourceVersions are opaque, but the apiserver backed by etcd
+// hands out increasing integers; anything else never counts as reached, which
+// leaves the replay to watchIdleTimeout.
+func reachedResourceVersion(rv, current string) bool {
+	v, err := strconv.ParseUint(rv, 10, 64)
+	if err != nil {
+		return false
+	}
+	cur, err := strconv.ParseUint(current, 10, 64)
+	if err != nil {
+		return false
+	}
+	return v >= cur
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// window is a daily time range of --allowed-window, optionally limited to some
+// weekdays. A range that ends before it starts spans midnight and belongs to
+// the day it starts on.
+type window struct {
+	days       map[time.Weekday]bool
+	start, end time.Duration
+}
+
+// parseWindow parses "HH:MM-HH:MM" optionally preceded by days, such as
+// "Mon-Fri 22:00-02:00" or "Sat,Sun 00:00-23:59".
+func parseWindow(s string) (*window, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("expected [days] HH:MM-HH:MM")
+	}
+
+	w := &window{}
+	if len(fields) == 2 {
+		days, err := parseWeekdays(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		w.days = days
+	}
+
+	bounds := strings.Split(fields[len(fields)-1], "-")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("expected HH:MM-HH:MM, got %q", fields[len(fields)-1])
+	}
+	var err error
+	if w.start, err = parseClock(bounds[0]); err != nil {
+		return nil, err
+	}
+	if w.end, err = parseClock(bounds[1]); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func parseWeekdays(s string) (map[time.Weekday]bool, error) {
+	days := map[time.Weekday]bool{}
+	for _, part := range strings.Split(strings.ToLower(s), ",") {
+		if from, to, ok := strings.Cut(part, "-"); ok {
+			first, ok1 := weekdays[from]
+			last, ok2 := weekdays[to]
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("unknown day range %q", part)
+			}
+			for d := first; ; d = (d + 1) % 7 {
+				days[d] = true
+				if d == last {
+					break
+				}
+			}
+			continue
+		}
+		d, ok := weekdays[part]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", part)
+		}
+		days[d] = true
+	}
+
+	return days, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains reports whether t falls into the window, in the location of t.
+func (w *window) contains(t time.Time) bool {
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	day := t.Weekday()
+	if w.start <= w.end {
+		return w.onDay(day) && clock >= w.start && clock < w.end
+	}
+	if clock >= w.start {
+		return w.onDay(day)
+	}
+	return clock < w.end && w.onDay((day+6)%7)
+}
+
+func (w *window) onDay(d time.Weekday) bool {
+	return w.days == nil || w.days[d]
+}